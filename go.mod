@@ -3,6 +3,11 @@ module github.com/ScooballyD/gator
 go 1.23.2
 
 require (
+	github.com/andybalholm/brotli v1.2.0 // direct
 	github.com/google/uuid v1.6.0 // direct
+	github.com/klauspost/compress v1.18.0 // direct
 	github.com/lib/pq v1.10.9 // direct
+	github.com/mattn/go-sqlite3 v1.14.33 // direct
+	golang.org/x/net v0.35.0 // direct
+	gopkg.in/yaml.v3 v3.0.1 // direct
 )
@@ -0,0 +1,199 @@
+// Package apperr gives gator's errors a kind, so the command line can pick an
+// exit code and hide database and driver details unless asked for them
+package apperr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type Kind int
+
+const (
+	Internal Kind = iota
+	NotFound
+	Conflict
+	Validation
+	Permission
+	Network
+	DB
+)
+
+var kindNames = map[Kind]string{
+	Internal:   "internal",
+	NotFound:   "not-found",
+	Conflict:   "conflict",
+	Validation: "validation",
+	Permission: "permission",
+	Network:    "network",
+	DB:         "db",
+}
+
+// Process exit codes, 1 is left for errors without a kind
+var exitCodes = map[Kind]int{
+	Internal:   1,
+	Validation: 2,
+	NotFound:   3,
+	Conflict:   4,
+	Permission: 5,
+	Network:    6,
+	DB:         7,
+}
+
+// Shown in place of a database cause, which rarely means anything to a user
+var friendlyCauses = map[Kind]string{
+	NotFound: "not found",
+	Conflict: "already exists",
+	DB:       "database error, run with --debug for details",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+type Error struct {
+	Kind Kind
+	// What gator was doing, ex: unable to find feed
+	Msg string
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Msg
+	}
+	return e.Msg + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Creates an error of the given kind without a cause
+func New(kind Kind, format string, args ...any) error {
+	return &Error{Kind: kind, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Adds context to err, the kind comes from err
+func Wrap(err error, format string, args ...any) error {
+	return &Error{Kind: classify(err), Msg: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Wraps an error from a database call, anything that isn't a missing row or
+// a broken constraint is a database error, even if the connection failed
+func WrapDB(err error, format string, args ...any) error {
+	kind, ok := classifyDB(err)
+	var e *Error
+	switch {
+	case ok:
+	case errors.As(err, &e):
+		kind = e.Kind
+	default:
+		kind = DB
+	}
+	return &Error{Kind: kind, Msg: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Like Wrap but with a kind the cause can't tell, ex: a bad url is a
+// validation error even though parsing it failed with a plain error
+func WrapKind(kind Kind, err error, format string, args ...any) error {
+	return &Error{Kind: kind, Msg: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Kind of the outermost gator error in the chain, or what the cause looks like
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return classify(err)
+}
+
+func classify(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	if kind, ok := classifyDB(err); ok {
+		return kind
+	}
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded):
+		return Network
+	case errors.Is(err, fs.ErrNotExist):
+		return NotFound
+	case errors.Is(err, fs.ErrPermission):
+		return Permission
+	}
+	return Internal
+}
+
+// Kind of an error that came from the database driver, false for anything else
+func classifyDB(err error) (Kind, bool) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound, true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23505":
+			return Conflict, true
+		case pqErr.Code == "23503" || pqErr.Code == "23514" || pqErr.Code.Class() == "22":
+			return Validation, true
+		}
+		return DB, true
+	}
+	if kind, ok := classifySQLite(err); ok {
+		return kind, true
+	}
+	if errors.Is(err, sql.ErrConnDone) || errors.Is(err, sql.ErrTxDone) {
+		return DB, true
+	}
+	return Internal, false
+}
+
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return exitCodes[KindOf(err)]
+}
+
+// Text to show for err: with debug the whole chain and its kind, otherwise
+// database causes are replaced by a short explanation
+func Message(err error, debug bool) string {
+	if debug {
+		return fmt.Sprintf("%v (%v)", err, KindOf(err))
+	}
+
+	var parts []string
+	var last *Error
+	for err != nil {
+		e, ok := err.(*Error)
+		if !ok {
+			break
+		}
+		parts = append(parts, e.Msg)
+		last = e
+		err = e.Err
+	}
+	if err != nil {
+		kind, fromDB := classifyDB(err)
+		if friendly, ok := friendlyCauses[kind]; ok && fromDB {
+			parts = append(parts, friendly)
+		} else if last != nil && last.Kind == DB {
+			parts = append(parts, friendlyCauses[DB])
+		} else {
+			parts = append(parts, err.Error())
+		}
+	}
+	return strings.Join(parts, ": ")
+}
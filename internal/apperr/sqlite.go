@@ -0,0 +1,24 @@
+//go:build cgo
+
+package apperr
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Kind of an error from the SQLite driver, which needs cgo
+func classifySQLite(err error) (Kind, bool) {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return Internal, false
+	}
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return Conflict, true
+	case sqlite3.ErrConstraintForeignKey, sqlite3.ErrConstraintCheck, sqlite3.ErrConstraintNotNull:
+		return Validation, true
+	}
+	return DB, true
+}
@@ -0,0 +1,8 @@
+//go:build !cgo
+
+package apperr
+
+// Without cgo there's no SQLite driver to get errors from
+func classifySQLite(err error) (Kind, bool) {
+	return Internal, false
+}
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Registers the flags shared by agg and the daemon
+func aggFlags(s *State, fs *flag.FlagSet) {
+	fs.Bool("download-enclosures", false, "download new enclosures from followed feeds")
+	downloadFlags(s, fs)
+	fs.Bool("prune", false, "delete posts outside the retention window after each pass")
+	pruneFlags(s, fs)
+	fs.String("metrics-addr", "", "serve Prometheus metrics on this address, ex: :9090")
+}
+
+func HandlerAggregate(s *State, cmd Command, user database.User) error {
+	agg, err := newAggregator(s, cmd)
+	if err != nil {
+		return err
+	}
+	return agg.run(context.Background())
+}
+
+// The agg loop, shared by agg in the foreground and the daemon
+type aggregator struct {
+	s        *State
+	cmd      Command
+	interval time.Duration
+	started  time.Time
+
+	withDownloads bool
+	withPrune     bool
+	metricsAddr   string
+	user          database.User
+	downloadOpts  downloadOptions
+	retention     pruneOptions
+
+	refreshes chan refreshRequest
+	reloads   chan chan error
+
+	mu       sync.Mutex
+	inFlight map[uuid.UUID]inFlightFeed
+	queued   []string
+	lastPass time.Time
+}
+
+type inFlightFeed struct {
+	Name  string    `json:"name"`
+	Url   string    `json:"url"`
+	Since time.Time `json:"since"`
+}
+
+// Feeds to fetch right away, the results are sent on reply once they're done
+type refreshRequest struct {
+	urls        []string
+	concurrency int
+	reply       chan []refreshRow
+}
+
+func newAggregator(s *State, cmd Command) (*aggregator, error) {
+	if len(cmd.Arguments) < 1 {
+		return nil, apperr.New(apperr.Validation, "the agg handler takes 1 argument: time between reqs\nEx: '1m'")
+	}
+	dur, err := time.ParseDuration(cmd.Arguments[0])
+	if err != nil {
+		return nil, apperr.WrapKind(apperr.Validation, err, "unable to parse duration")
+	}
+	if dur <= 0 {
+		return nil, apperr.New(apperr.Validation, "interval must be more than 0")
+	}
+
+	a := &aggregator{
+		s:             s,
+		cmd:           cmd,
+		interval:      dur,
+		started:       time.Now(),
+		withDownloads: cmd.boolFlag("download-enclosures"),
+		withPrune:     cmd.boolFlag("prune"),
+		metricsAddr:   cmd.stringFlag("metrics-addr"),
+		refreshes:     make(chan refreshRequest, 16),
+		reloads:       make(chan chan error),
+		inFlight:      map[uuid.UUID]inFlightFeed{},
+	}
+	err = a.configure()
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Works out the options that depend on the config file, again after a reload
+func (a *aggregator) configure() error {
+	var err error
+	if a.withPrune {
+		a.retention, err = pruneOptionsFrom(a.s, a.cmd)
+		if err != nil {
+			return err
+		}
+	}
+	if a.withDownloads {
+		a.downloadOpts, err = downloadOptionsFrom(a.s, a.cmd)
+		if err != nil {
+			return err
+		}
+		a.user, err = a.s.dbq.GetUser(context.Background(), a.s.point.Current_user_name)
+		if err != nil {
+			return apperr.WrapDB(err, "unable to find current user")
+		}
+	}
+	return nil
+}
+
+// Runs a pass every interval until ctx is done, refreshes and reloads
+// are handled between passes
+func (a *aggregator) run(ctx context.Context) error {
+	if a.metricsAddr != "" {
+		err := a.s.serveMetrics(a.metricsAddr)
+		if err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	a.s.log.Info("aggregator started",
+		"interval", a.interval,
+		"downloads", a.withDownloads,
+		"prune", a.withPrune)
+	err := a.pass(ctx)
+	for err == nil {
+		select {
+		case <-ctx.Done():
+			a.s.log.Info("aggregator stopped")
+			return nil
+		case <-ticker.C:
+			err = a.pass(ctx)
+		case req := <-a.refreshes:
+			req.reply <- a.refresh(ctx, req)
+		case reply := <-a.reloads:
+			reply <- a.reload()
+		}
+	}
+	return err
+}
+
+func (a *aggregator) pass(ctx context.Context) error {
+	defer func() {
+		a.mu.Lock()
+		a.lastPass = time.Now()
+		a.mu.Unlock()
+	}()
+
+	// one broken feed shouldn't stop the others, a broken database stops everything,
+	// scrapeFeed has already logged what went wrong
+	feed, found, err := nextFeed(ctx, a.s)
+	if found {
+		_, err = a.track(ctx, feed)
+	}
+	if apperr.KindOf(err) == apperr.DB {
+		return err
+	}
+
+	if a.metricsAddr != "" {
+		err = a.s.updateOverdue(ctx, a.interval)
+		if err != nil {
+			a.s.log.Error("unable to update metrics", "err", err)
+		}
+	}
+
+	if a.withDownloads {
+		// only episodes that arrived while agg is running, not the whole back catalogue
+		err = a.s.downloadPending(ctx, a.user, a.started, a.downloadOpts)
+		if err != nil {
+			a.s.log.Error("downloads failed", "err", err)
+		}
+	}
+
+	if a.withPrune {
+		n, err := a.s.prunePosts(ctx, a.retention)
+		if err != nil {
+			a.s.log.Error("prune failed", "err", err)
+		} else if n > 0 {
+			a.s.log.Info("pruned posts", "posts", n)
+		}
+	}
+	return nil
+}
+
+// Scrapes feed while status lists it as in flight
+func (a *aggregator) track(ctx context.Context, feed database.Feed) (int, error) {
+	a.mu.Lock()
+	a.inFlight[feed.ID] = inFlightFeed{Name: feed.Name, Url: RedactURL(feed.Url), Since: time.Now()}
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.inFlight, feed.ID)
+		a.mu.Unlock()
+	}()
+	started := time.Now()
+	n, err := scrapeFeed(ctx, a.s, feed)
+	if err == nil {
+		a.s.log.Info("fetched feed", "feed_id", feed.ID, "url", RedactURL(feed.Url), "new", n, "duration", time.Since(started))
+	}
+	return n, err
+}
+
+// Queues urls to be fetched after the current pass and waits for the results
+func (a *aggregator) requestRefresh(ctx context.Context, urls []string, concurrency int) ([]refreshRow, error) {
+	req := refreshRequest{urls: urls, concurrency: concurrency, reply: make(chan []refreshRow, 1)}
+	a.mu.Lock()
+	a.queued = append(a.queued, urls...)
+	a.mu.Unlock()
+
+	select {
+	case a.refreshes <- req:
+	case <-ctx.Done():
+		return nil, apperr.New(apperr.Conflict, "the aggregator is shutting down")
+	}
+	select {
+	case results := <-req.reply:
+		return results, nil
+	case <-ctx.Done():
+		return nil, apperr.New(apperr.Conflict, "the aggregator is shutting down")
+	}
+}
+
+func (a *aggregator) refresh(ctx context.Context, req refreshRequest) []refreshRow {
+	a.mu.Lock()
+	a.queued = a.queued[min(len(req.urls), len(a.queued)):]
+	a.mu.Unlock()
+	return refreshFeeds(ctx, a.s, req.urls, req.concurrency, a.track)
+}
+
+// Rereads the config file, settings given as flags keep their value,
+// a new db_url only takes effect after a restart
+func (a *aggregator) reload() error {
+	cfg, err := Read()
+	if err != nil {
+		return err
+	}
+	if cfg.Db_url != a.s.point.Db_url {
+		a.s.log.Warn("db_url changed, restart the daemon to use it")
+		cfg.Db_url = a.s.point.Db_url
+	}
+
+	old := *a.s.point
+	*a.s.point = cfg
+	err = a.configure()
+	if err == nil {
+		err = a.s.setLogger(cfg)
+	}
+	if err != nil {
+		*a.s.point = old
+		a.configure()
+		return err
+	}
+	a.s.log.Info("config reloaded")
+	return nil
+}
+
+// Asks the loop to reload between passes and waits for the outcome
+func (a *aggregator) requestReload(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case a.reloads <- reply:
+	case <-ctx.Done():
+		return apperr.New(apperr.Conflict, "the aggregator is shutting down")
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return apperr.New(apperr.Conflict, "the aggregator is shutting down")
+	}
+}
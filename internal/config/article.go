@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/readability"
+)
+
+// Downloads the page a post links to and extracts the article body as sanitized HTML
+func (s State) fetchArticle(ctx context.Context, pageURL string) (string, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", apperr.WrapKind(apperr.Validation, err, "invalid url")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", apperr.Wrap(err, "unable to send request")
+	}
+	req.Header.Add("User-Agent", "gator")
+	req.Header.Add("Accept", "text/html, application/xhtml+xml;q=0.9")
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return "", apperr.Wrap(redactError(err), "response error")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", apperr.New(apperr.Network, "unexpected response status: %v", resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != "text/html" && !strings.HasSuffix(mediaType, "xhtml+xml")) {
+			return "", apperr.New(apperr.Network, "unexpected content type %v: expected an html page", ct)
+		}
+	}
+
+	limit := s.point.Max_feed_bytes
+	if limit <= 0 {
+		limit = defaultMaxFeedBytes
+	}
+	// redirects may have moved us, links in the page are relative to where we ended up
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+
+	article, err := readability.Extract(&cappedReader{r: resp.Body, limit: limit}, base)
+	if err != nil {
+		return "", apperr.Wrap(err, "unable to extract article")
+	}
+	return article.Content, nil
+}
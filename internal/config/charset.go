@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+var latin1Table = func() *[256]rune {
+	var t [256]rune
+	for i := range t {
+		t[i] = rune(i)
+	}
+	return &t
+}()
+
+// Windows-1252 only differs from ISO-8859-1 in the 0x80-0x9F range,
+// undefined bytes are passed through like the WHATWG encoding spec does
+var windows1252Table = func() *[256]rune {
+	t := *latin1Table
+	high := [32]rune{
+		0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+		0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+		0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+		0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+	}
+	copy(t[0x80:0xA0], high[:])
+	return &t
+}()
+
+// Used as xml.Decoder.CharsetReader, converts single byte encodings to UTF-8
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "utf-8", "utf8":
+		return input, nil
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1", "l1", "us-ascii", "ascii":
+		return &decodingReader{src: input, table: latin1Table}, nil
+	case "windows-1252", "cp1252", "x-cp1252":
+		return &decodingReader{src: input, table: windows1252Table}, nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", label)
+}
+
+type decodingReader struct {
+	src   io.Reader
+	table *[256]rune
+	raw   [4096]byte
+	out   []byte
+	off   int
+	err   error
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	for d.off == len(d.out) {
+		if d.err != nil {
+			return 0, d.err
+		}
+		var n int
+		n, d.err = d.src.Read(d.raw[:])
+		d.out, d.off = d.out[:0], 0
+		for _, c := range d.raw[:n] {
+			d.out = utf8.AppendRune(d.out, d.table[c])
+		}
+	}
+
+	n := copy(p, d.out[d.off:])
+	d.off += n
+	return n, nil
+}
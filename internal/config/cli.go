@@ -0,0 +1,459 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+)
+
+type Command struct {
+	Name      string
+	Arguments []string
+	// Flags from the command's spec, parsed by Run
+	Flags *flag.FlagSet
+}
+
+type Commands struct {
+	Library map[string]CommandSpec
+}
+
+// What kind of value an argument or flag takes, used to complete it
+type ArgKind int
+
+const (
+	argText ArgKind = iota
+	argFeed
+	argFollowed
+	argUser
+	argTag
+	argPost
+	argFilter
+	argFile
+	argCommand
+	argChoice
+)
+
+type Arg struct {
+	Name string
+	Kind ArgKind
+	// Values an argChoice argument accepts
+	Choices  []string
+	Optional bool
+	// The last argument can be given any number of times
+	Repeated bool
+}
+
+// Describes a command for the registry: how to run it, what it accepts and
+// how it's documented in help
+type CommandSpec struct {
+	Name    string
+	Summary string
+	// Longer description shown by help <command>
+	Details  string
+	Args     []Arg
+	Examples []string
+	// Registers the command's flags, defaults can come from the config
+	Flags func(s *State, fs *flag.FlagSet)
+	// Values taken by flags, keyed by flag name
+	FlagArgs map[string]Arg
+	// Handler for commands that work without a user
+	Run func(s *State, cmd Command) error
+	// Handler for commands that need a logged in user with at least Role
+	RunAs func(s *State, cmd Command, user database.User) error
+	Role  string
+	// Left out of help, used by the shell completion scripts
+	Hidden bool
+	// Arguments are passed on as given, without parsing flags
+	RawArgs bool
+	// Runs against a database whose schema isn't at gator's version
+	AnySchema bool
+}
+
+// Creates a registry with every gator command
+func NewCommands() Commands {
+	cmds := Commands{Library: make(map[string]CommandSpec)}
+	for _, spec := range builtinCommands() {
+		cmds.Register(spec)
+	}
+	cmds.Register(CommandSpec{
+		Name:      "help",
+		Summary:   "Show the commands, or how to use one of them",
+		Args:      []Arg{{Name: "command", Kind: argCommand, Optional: true}},
+		Examples:  []string{"gator help browse"},
+		Run:       cmds.help,
+		AnySchema: true,
+	})
+	cmds.Register(CommandSpec{
+		Name:    "completion",
+		Summary: "Print a shell completion script",
+		Details: "Completes commands, flags, feed urls, usernames, tags and post ids.\n" +
+			"Load it from your shell's startup file.",
+		Args: []Arg{{Name: "shell", Kind: argChoice, Choices: []string{"bash", "zsh", "fish"}}},
+		Examples: []string{
+			`echo 'source <(gator completion bash)' >> ~/.bashrc`,
+			`gator completion zsh > "${fpath[1]}/_gator"`,
+			"gator completion fish > ~/.config/fish/completions/gator.fish",
+		},
+		Run:       HandlerCompletion,
+		AnySchema: true,
+	})
+	cmds.Register(CommandSpec{
+		Name:      completeCommand,
+		Hidden:    true,
+		RawArgs:   true,
+		Run:       cmds.complete,
+		AnySchema: true,
+	})
+	return cmds
+}
+
+// Registers new command into library
+func (cmds Commands) Register(spec CommandSpec) {
+	if spec.RunAs != nil {
+		role := spec.Role
+		if role == "" {
+			role = RoleReadOnly
+		}
+		spec.Run = MiddlewareRole(role, spec.RunAs)
+	}
+	cmds.Library[spec.Name] = spec
+
+	_, exist := cmds.Library[spec.Name]
+	if !exist {
+		fmt.Printf("failed to add %v to commands\n", spec.Name)
+	}
+}
+
+// Whether running args needs the database schema to be up to date,
+// help and unknown commands don't
+func (cmds Commands) NeedsSchema(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	spec, exist := cmds.Library[args[0]]
+	if !exist || spec.AnySchema {
+		return false
+	}
+	return spec.RawArgs || !asksHelp(args[1:])
+}
+
+// Whether args only ask for help: no command, -h or --help, help itself or a
+// command followed by --help. Help needs no database
+func (cmds Commands) WantsHelp(args []string) bool {
+	if len(args) == 0 || asksHelp(args[:1]) || args[0] == "help" {
+		return true
+	}
+	spec, exist := cmds.Library[args[0]]
+	return exist && !spec.RawArgs && asksHelp(args[1:])
+}
+
+// Prints the help args ask for, flag defaults come from cfg
+func (cmds Commands) Help(cfg Config, args []string) error {
+	if len(args) == 0 || asksHelp(args[:1]) {
+		return cmds.PrintUsage(os.Stdout)
+	}
+	s := &State{point: &cfg, output: formatText}
+	return cmds.Run(s, Command{Name: args[0], Arguments: args[1:]})
+}
+
+func asksHelp(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "-h" || arg == "-help" || arg == "--help" {
+			return true
+		}
+	}
+	return false
+}
+
+func (cmds Commands) Run(s *State, cmd Command) error {
+	spec, exist := cmds.Library[cmd.Name]
+	if !exist {
+		return cmds.unknown(cmd.Name)
+	}
+
+	if spec.RawArgs {
+		return spec.Run(s, cmd)
+	}
+
+	fs := spec.flagSet(s)
+	// the global flags can follow the command name too
+	output := fs.String("output", "", "")
+	debug := fs.Bool("debug", s.debug, "")
+	args, err := parseFlags(fs, cmd.Arguments)
+	if errors.Is(err, flag.ErrHelp) {
+		return printCommandHelp(os.Stdout, s, spec)
+	}
+	if err != nil {
+		return apperr.New(apperr.Validation, "%v\nusage: %v", err, spec.usage())
+	}
+	s.debug = *debug
+	if *output != "" {
+		err = s.SetOutput(*output)
+		if err != nil {
+			return err
+		}
+	}
+	err = spec.checkArgs(args)
+	if err != nil {
+		return err
+	}
+
+	cmd.Arguments = args
+	cmd.Flags = fs
+	return spec.Run(s, cmd)
+}
+
+func (cmds Commands) unknown(name string) error {
+	if near := cmds.suggest(name); near != "" {
+		return apperr.New(apperr.Validation, "unknown command %q, did you mean %v?", name, near)
+	}
+	return apperr.New(apperr.Validation, "unknown command %q, see gator help", name)
+}
+
+// Closest command to a mistyped name, empty when nothing is close
+func (cmds Commands) suggest(name string) string {
+	best, bestDist := "", 3
+	for _, spec := range cmds.visible() {
+		if strings.HasPrefix(spec.Name, name) && len(name) > 1 {
+			return spec.Name
+		}
+		if d := editDistance(name, spec.Name); d < bestDist {
+			best, bestDist = spec.Name, d
+		}
+	}
+	return best
+}
+
+// Levenshtein distance between two words
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// Commands shown in help, sorted by name
+func (cmds Commands) visible() []CommandSpec {
+	var specs []CommandSpec
+	for _, spec := range cmds.Library {
+		if !spec.Hidden {
+			specs = append(specs, spec)
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+func (cmds Commands) help(s *State, cmd Command) error {
+	if len(cmd.Arguments) == 0 {
+		return cmds.PrintUsage(os.Stdout)
+	}
+	spec, exist := cmds.Library[cmd.Arguments[0]]
+	if !exist {
+		return cmds.unknown(cmd.Arguments[0])
+	}
+	return printCommandHelp(os.Stdout, s, spec)
+}
+
+// Lists every command with its summary
+func (cmds Commands) PrintUsage(w io.Writer) error {
+	fmt.Fprintln(w, "gator is a command line RSS reader")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  gator [--output format] [--debug] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, spec := range cmds.visible() {
+		fmt.Fprintf(tw, "  %v\t%v\n", spec.Name, spec.Summary)
+	}
+	err := tw.Flush()
+	if err != nil {
+		return apperr.Wrap(err, "unable to print help")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'gator help <command>' or 'gator <command> --help' for more about a command.")
+	return nil
+}
+
+func printCommandHelp(w io.Writer, s *State, spec CommandSpec) error {
+	fmt.Fprintf(w, "Usage: %v\n\n", spec.usage())
+	if spec.Summary != "" {
+		fmt.Fprintln(w, spec.Summary)
+	}
+	if spec.Details != "" {
+		fmt.Fprintln(w, spec.Details)
+	}
+	if spec.RunAs != nil && spec.Role != "" && spec.Role != RoleReadOnly {
+		fmt.Fprintf(w, "Needs the %v role.\n", spec.Role)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fs := spec.flagSet(s)
+	if flags := describeFlags(fs); len(flags) > 0 {
+		fmt.Fprintln(tw, "\nFlags:")
+		for _, f := range flags {
+			fmt.Fprintf(tw, "  %v\t%v\n", f[0], f[1])
+		}
+	}
+	fmt.Fprintln(tw, "\nGlobal flags:")
+	fmt.Fprintf(tw, "  --output format\tprint listings as %v\n", strings.Join(outputFormats(), ", "))
+	fmt.Fprintln(tw, "  --debug\tshow the full cause of errors")
+	fmt.Fprintln(tw, "  -h, --help\tshow this help")
+	err := tw.Flush()
+	if err != nil {
+		return apperr.Wrap(err, "unable to print help")
+	}
+
+	if len(spec.Examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, ex := range spec.Examples {
+			fmt.Fprintf(w, "  %v\n", ex)
+		}
+	}
+	return nil
+}
+
+// Flag names and descriptions, aliases sharing a value are listed together
+func describeFlags(fs *flag.FlagSet) [][2]string {
+	var out [][2]string
+	seen := map[flag.Value]int{}
+	fs.VisitAll(func(f *flag.Flag) {
+		name := "--" + f.Name
+		if len(f.Name) == 1 {
+			name = "-" + f.Name
+		}
+		if i, ok := seen[f.Value]; ok {
+			if len(f.Name) == 1 {
+				out[i][0] = name + ", " + out[i][0]
+			} else {
+				out[i][0] += ", " + name
+			}
+			return
+		}
+		if kind, _ := flag.UnquoteUsage(f); kind != "" {
+			name += " " + kind
+		}
+		usage := f.Usage
+		if !isBoolFlag(f) && f.DefValue != "" && f.DefValue != "0" {
+			usage += fmt.Sprintf(" (default %v)", f.DefValue)
+		}
+		seen[f.Value] = len(out)
+		out = append(out, [2]string{name, usage})
+	})
+	return out
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (spec CommandSpec) flagSet(s *State) *flag.FlagSet {
+	fs := newFlagSet(spec.Name)
+	if spec.Flags != nil {
+		spec.Flags(s, fs)
+	}
+	return fs
+}
+
+// One line synopsis, ex: gator tag <url> <tag>...
+func (spec CommandSpec) usage() string {
+	parts := []string{"gator", spec.Name}
+	for _, a := range spec.Args {
+		name := a.Name
+		if a.Kind == argChoice {
+			name = strings.Join(a.Choices, "|")
+		}
+		if a.Repeated {
+			name += "..."
+		}
+		if a.Optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	if spec.Flags != nil {
+		parts = append(parts, "[flags]")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (spec CommandSpec) checkArgs(args []string) error {
+	required, max := 0, len(spec.Args)
+	for _, a := range spec.Args {
+		if !a.Optional {
+			required++
+		}
+		if a.Repeated {
+			max = -1
+		}
+	}
+	if len(args) < required || (max >= 0 && len(args) > max) {
+		return apperr.New(apperr.Validation, "%v takes %v\nusage: %v", spec.Name, describeCount(required, max), spec.usage())
+	}
+	for i, a := range spec.Args {
+		if a.Kind != argChoice || i >= len(args) {
+			continue
+		}
+		if !contains(a.Choices, args[i]) {
+			return apperr.New(apperr.Validation, "unknown %v %q, expected %v\nusage: %v", a.Name, args[i], strings.Join(a.Choices, ", "), spec.usage())
+		}
+	}
+	return nil
+}
+
+func describeCount(required, max int) string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%v arguments", n)
+	}
+	switch {
+	case max == 0:
+		return "no arguments"
+	case max < 0:
+		return "at least " + plural(required)
+	case required == max:
+		return plural(required)
+	case required == 0:
+		return "at most " + plural(max)
+	default:
+		return fmt.Sprintf("%v to %v arguments", required, max)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
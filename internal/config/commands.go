@@ -2,30 +2,38 @@ package config
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"math"
+	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
+	"github.com/ScooballyD/gator/internal/apperr"
 	"github.com/ScooballyD/gator/internal/database"
+	"github.com/ScooballyD/gator/internal/render"
 	"github.com/google/uuid"
 )
 
-type Command struct {
-	Name      string
-	Arguments []string
-}
-
-type Commands struct {
-	Library map[string]func(*State, Command) error
-}
-
 func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 	if len(cmd.Arguments) < 2 {
-		return fmt.Errorf("addfeed takes two arguments: name, url")
+		return apperr.New(apperr.Validation, "addfeed takes two arguments: name, url")
 	}
 
-	//
+	fedURL, cred, err := splitURLCredential(cmd.Arguments[1])
+	if err != nil {
+		return err
+	}
+	if cred != nil {
+		// fail before creating the feed rather than storing it without its login
+		if _, err := s.credentialKey(); err != nil {
+			return apperr.Wrap(err, "url contains credentials that can't be stored")
+		}
+	}
 
 	feed, err := s.dbq.CreateFeed(
 		context.Background(),
@@ -34,11 +42,18 @@ func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 			Name:      cmd.Arguments[0],
-			Url:       cmd.Arguments[1],
+			Url:       fedURL,
 			UserID:    user.ID,
 		})
 	if err != nil {
-		return fmt.Errorf("unable to create feed: %v", err)
+		return apperr.WrapDB(err, "unable to create feed")
+	}
+
+	if cred != nil {
+		err = s.saveFeedCredential(context.Background(), feed.ID, *cred)
+		if err != nil {
+			return err
+		}
 	}
 
 	_, err = s.dbq.CreateFeedFollow(
@@ -51,72 +66,488 @@ func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 			FeedID:    feed.ID,
 		})
 	if err != nil {
-		return fmt.Errorf("unable to follow feed: %v", err)
+		return apperr.WrapDB(err, "unable to follow feed")
 	}
 
 	fmt.Printf(
 		"Feed successfully created\nID: %v\nCreatedAt: %v\nName: %v\nUrl: %v\nUser: %v",
-		feed.ID, feed.CreatedAt, feed.Name, feed.Url, user.Name)
+		feed.ID, feed.CreatedAt, feed.Name, RedactURL(feed.Url), user.Name)
 	return nil
 }
 
-func HandlerAggregate(s *State, cmd Command) error {
-	if len(cmd.Arguments) < 1 {
-		return fmt.Errorf("the agg handler takes 1 argument: time between reqs\nEx: '1m'")
+func HandlerBrowse(s *State, cmd Command, user database.User) error {
+	args := cmd.Arguments
+	verbose := cmd.boolFlag("verbose")
+	lim := 2
+	var err error
+	if len(args) > 0 {
+		lim, err = strconv.Atoi(args[0])
+		if err != nil {
+			return apperr.WrapKind(apperr.Validation, err, "unable to process limit %v", args[0])
+		}
+	}
+
+	stored, err := s.dbq.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to get filters")
+	}
+	rules := make([]database.FilterRule, 0, len(stored))
+	for _, r := range stored {
+		rules = append(rules, database.FilterRule{
+			ID:            r.ID,
+			CreatedAt:     r.CreatedAt,
+			UserID:        r.UserID,
+			FeedID:        r.FeedID,
+			TitlePattern:  r.TitlePattern,
+			AuthorPattern: r.AuthorPattern,
+			Keyword:       r.Keyword,
+			Action:        r.Action,
+		})
+	}
+	filters := compileRules(s.log, rules)
+
+	follows, err := s.dbq.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to retrieve followed feeds")
+	}
+	feedNames := map[uuid.UUID]string{}
+	for _, f := range follows {
+		feedNames[f.FeedID] = f.FeedName
+	}
+
+	var rows []postRow
+	var listed []database.Post
+	shown := map[string]bool{}
+	// posts skipped below would leave the list short, so keep reading pages
+	// until it has lim posts or there are no more
+	for offset := 0; len(rows) < lim; offset += lim {
+		posts, err := s.dbq.GetPostsForUser(
+			context.Background(),
+			database.GetPostsForUserParams{
+				UserID:        user.ID,
+				Limit:         int32(lim),
+				Offset:        int32(offset),
+				Tag:           strings.Trim(strings.TrimSpace(cmd.stringFlag("tag")), "/"),
+				IncludeHidden: cmd.boolFlag("hidden"),
+			})
+		if err != nil {
+			return apperr.WrapDB(err, "unable to get posts")
+		}
+		first := len(rows)
+		links := []string{}
+		for _, pst := range posts {
+			if len(rows) == lim {
+				break
+			}
+			// the same article from two followed feeds is listed once
+			link := stripTracking(pst.Post.Url)
+			if link != "" && shown[link] {
+				continue
+			}
+			shown[link] = true
+
+			// rules are checked again so ones added since scraping apply to older posts too
+			for _, f := range filters {
+				if !f.matches(pst.Post) {
+					continue
+				}
+				switch f.Action {
+				case actionHide:
+					pst.Hidden = true
+				case actionStar:
+					pst.Starred = true
+				case actionMarkRead:
+					pst.ReadAt.Valid = true
+				}
+			}
+			if pst.Hidden && !cmd.boolFlag("hidden") {
+				continue
+			}
+			if link != "" {
+				links = append(links, link)
+			}
+
+			rows = append(rows, postRow{
+				ID:          pst.Post.ID.String()[:8],
+				Feed:        feedNames[pst.Post.FeedID],
+				Title:       pst.Post.Title,
+				PublishedAt: pst.Post.PublishedAt,
+				Url:         pst.Post.Url,
+				Author:      pst.Post.Author,
+				Starred:     pst.Starred,
+				Read:        pst.ReadAt.Valid,
+				Hidden:      pst.Hidden,
+				Description: render.Text(pst.Post.Description, render.Options{Width: math.MaxInt32}),
+			})
+			listed = append(listed, pst.Post)
+		}
+
+		err = s.addOtherFeeds(user, rows[first:], listed[first:], links)
+		if err != nil {
+			return err
+		}
+		if len(posts) < lim {
+			break
+		}
+	}
+
+	return s.render(rows, func() error {
+		for i, row := range rows {
+			pst := listed[i]
+			fmt.Printf("\ntitle: %v\n", row.Title)
+			fmt.Printf("--id: %v\n", row.ID)
+			fmt.Printf("--feed: %v\n", row.Feed)
+			if flags := postFlags(row); flags != "" {
+				fmt.Printf("--%v\n", flags)
+			}
+			fmt.Printf("--published at: %v\n", row.PublishedAt)
+			fmt.Printf("--description:\n%v\n", render.Text(pst.Description, render.Options{
+				Width:    terminalWidth(),
+				MaxLines: cmd.intFlag("lines"),
+				Indent:   "    ",
+			}))
+			fmt.Printf("--url: %v\n", row.Url)
+			if len(row.AlsoIn) > 0 {
+				fmt.Printf("--also appeared in: %v\n", strings.Join(row.AlsoIn, ", "))
+			}
+			if verbose {
+				err := s.printPostDetails(context.Background(), pst)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func HandlerChown(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) != 2 {
+		return apperr.New(apperr.Validation, "the chown handler takes 2 arguments: url, user")
 	}
 
-	dur, err := time.ParseDuration(cmd.Arguments[0])
+	feed, err := s.dbq.GetFeed(context.Background(), cmd.Arguments[0])
+	if err != nil {
+		return apperr.WrapDB(err, "unable to find feed")
+	}
+	if !canManageFeed(user, feed) {
+		return apperr.New(apperr.Permission, "only the user who added %v or an admin can give it away", feed.Name)
+	}
+
+	owner, err := s.dbq.GetUser(context.Background(), cmd.Arguments[1])
 	if err != nil {
-		return fmt.Errorf("unable to parse duration: %v", err)
+		return apperr.WrapDB(err, "unable to find user")
 	}
 
-	ticker := time.NewTicker(dur)
-	fmt.Printf("Collecting feed every %v\n", cmd.Arguments[0])
-	for ; ; <-ticker.C {
-		scrapeFeeds(s)
+	err = s.dbq.SetFeedOwner(
+		context.Background(),
+		database.SetFeedOwnerParams{
+			ID:     feed.ID,
+			UserID: owner.ID,
+		})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to change owner")
 	}
+
+	fmt.Printf("%v now belongs to %v\n", feed.Name, owner.Name)
+	return nil
 }
 
-func HandlerBrowse(s *State, cmd Command, user database.User) error {
-	lim := 2
+func HandlerDelUser(s *State, cmd Command, user database.User) error {
+	args := cmd.Arguments
+	if len(args) != 1 {
+		return apperr.New(apperr.Validation, "the deluser handler takes 1 argument: name")
+	}
+
+	target, err := s.dbq.GetUser(context.Background(), args[0])
+	if err != nil {
+		return apperr.WrapDB(err, "unknown user")
+	}
+	if target.ID != user.ID && !hasRole(user, RoleAdmin) {
+		return apperr.New(apperr.Permission, "only admins can delete other users")
+	}
+
+	// feeds others still follow are handed over instead of deleted with their owner
+	var successor database.User
+	switch {
+	case cmd.stringFlag("to") != "":
+		successor, err = s.dbq.GetUser(context.Background(), cmd.stringFlag("to"))
+	case target.ID != user.ID:
+		successor = user
+	default:
+		successor, err = s.dbq.GetUserSuccessor(context.Background(), target.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return apperr.WrapDB(err, "unable to find user to take over feeds")
+	}
+	if successor.ID == target.ID {
+		return apperr.New(apperr.Validation, "--to has to name a different user")
+	}
+
+	if !cmd.boolFlag("yes") {
+		ok, err := confirm(fmt.Sprintf("Delete %v along with their follows, stars and filters?", target.Name))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("user not deleted")
+		}
+	}
+
+	if successor.ID != uuid.Nil {
+		_, err = s.dbq.TransferFeeds(
+			context.Background(),
+			database.TransferFeedsParams{
+				ToUserID:   successor.ID,
+				FromUserID: target.ID,
+			})
+		if err != nil {
+			return apperr.WrapDB(err, "unable to hand over feeds")
+		}
+		admins, err := s.dbq.CountAdmins(context.Background())
+		if err != nil {
+			return apperr.WrapDB(err, "unable to count admins")
+		}
+		if target.Role == RoleAdmin && admins == 1 && successor.Role != RoleAdmin {
+			// never leave the place without an admin
+			err = s.dbq.SetUserRole(
+				context.Background(),
+				database.SetUserRoleParams{
+					ID:   successor.ID,
+					Role: RoleAdmin,
+				})
+			if err != nil {
+				return apperr.WrapDB(err, "unable to make %v admin", successor.Name)
+			}
+		}
+	}
+
+	err = s.dbq.DeleteUser(context.Background(), target.ID)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to delete user")
+	}
+	_, err = s.dbq.DeleteOrphanedFeeds(context.Background())
+	if err != nil {
+		return apperr.WrapDB(err, "unable to remove unfollowed feeds")
+	}
+
+	if target.Name == s.point.Current_user_name {
+		err = s.point.SetUser("")
+		if err != nil {
+			return apperr.Wrap(err, "unable to log out")
+		}
+	}
+	fmt.Printf("Deleted %v\n", target.Name)
+	return nil
+}
+
+func HandlerDownload(s *State, cmd Command, user database.User) error {
+	args := cmd.Arguments
+	if len(args) < 1 {
+		return apperr.New(apperr.Validation, "the download handler takes at least 1 argument: post id")
+	}
+
+	opts, err := downloadOptionsFrom(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	var eps []episode
+	for _, ref := range args {
+		pst, err := s.findPost(context.Background(), user, ref)
+		if err != nil {
+			return err
+		}
+		encs, err := s.dbq.GetEnclosuresForPost(context.Background(), pst.ID)
+		if err != nil {
+			return apperr.WrapDB(err, "unable to get enclosures")
+		}
+		if len(encs) == 0 {
+			return apperr.New(apperr.NotFound, "post %v has no enclosures", pst.Title)
+		}
+		for _, enc := range encs {
+			eps = append(eps, episode{
+				ID:        enc.ID,
+				Url:       enc.Url,
+				Type:      enc.Type,
+				Length:    enc.Length,
+				PostTitle: enc.PostTitle,
+				FeedName:  enc.FeedName,
+			})
+		}
+	}
+
+	return s.downloadEpisodes(context.Background(), user, eps, opts)
+}
+
+func HandlerEpisodes(s *State, cmd Command, user database.User) error {
+	lim := 10
 	var err error
 
 	if len(cmd.Arguments) > 0 {
 		lim, err = strconv.Atoi(cmd.Arguments[0])
 		if err != nil {
-			return fmt.Errorf("unable to process limit %v: %v", cmd.Arguments[0], err)
+			return apperr.WrapKind(apperr.Validation, err, "unable to process limit %v", cmd.Arguments[0])
 		}
 	}
 
-	posts, err := s.dbq.GetPostsForUser(
+	encs, err := s.dbq.GetEnclosuresForUser(
 		context.Background(),
-		database.GetPostsForUserParams{
+		database.GetEnclosuresForUserParams{
 			UserID: user.ID,
 			Limit:  int32(lim),
 		})
 	if err != nil {
-		return fmt.Errorf("unable to get posts: %v", err)
+		return apperr.WrapDB(err, "unable to get episodes")
+	}
+
+	rows := make([]episodeRow, 0, len(encs))
+	for _, enc := range encs {
+		rows = append(rows, episodeRow{
+			PostID:       enc.PostID.String()[:8],
+			Feed:         enc.FeedName,
+			Title:        enc.PostTitle,
+			PublishedAt:  enc.PublishedAt,
+			Url:          enc.Url,
+			Type:         enc.Type,
+			Bytes:        enc.Length,
+			DownloadedTo: enc.DownloadedPath.String,
+		})
+	}
+
+	return s.render(rows, func() error {
+		for _, row := range rows {
+			size := "unknown size"
+			if row.Bytes > 0 {
+				size = formatBytes(row.Bytes)
+			}
+			fmt.Printf("\n%v - %v\n", row.Feed, row.Title)
+			fmt.Printf("--post: %v\n", row.PostID)
+			fmt.Printf("--published at: %v\n", row.PublishedAt)
+			fmt.Printf("--media: %v (%v, %v)\n", row.Url, row.Type, size)
+			if row.DownloadedTo != "" {
+				fmt.Printf("--downloaded to: %v\n", row.DownloadedTo)
+			}
+		}
+		return nil
+	})
+}
+
+func HandlerExport(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) > 1 {
+		return apperr.New(apperr.Validation, "the export handler takes at most 1 argument: file")
+	}
+
+	follows, err := s.taggedFollows(context.Background(), user)
+	if err != nil {
+		return err
+	}
+	doc := buildOPML(follows, fmt.Sprintf("Feeds followed by %v", user.Name))
+
+	if len(cmd.Arguments) == 0 {
+		return writeOPML(os.Stdout, doc)
+	}
+
+	f, err := os.Create(cmd.Arguments[0])
+	if err != nil {
+		return apperr.Wrap(err, "unable to create file")
+	}
+	err = writeOPML(f, doc)
+	closeErr := f.Close()
+	if err != nil {
+		return apperr.Wrap(err, "unable to write opml")
+	}
+	if closeErr != nil {
+		return apperr.Wrap(closeErr, "unable to write opml")
+	}
+	fmt.Printf("Exported %v feeds to %v\n", len(follows), cmd.Arguments[0])
+	return nil
+}
+
+func HandlerFeedAuth(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) < 2 {
+		return apperr.New(apperr.Validation, "feedauth takes at least 2 arguments: url, basic|bearer|query|none")
+	}
+
+	feed, err := s.dbq.GetFeed(context.Background(), cmd.Arguments[0])
+	if err != nil {
+		return apperr.WrapDB(err, "unable to find feed")
+	}
+	if !canManageFeed(user, feed) {
+		return apperr.New(apperr.Permission, "only the user who added %v or an admin can change its credentials", feed.Name)
+	}
+
+	var cred feedCredential
+	args := cmd.Arguments[2:]
+	switch cmd.Arguments[1] {
+	case authBasic:
+		if len(args) != 2 {
+			return apperr.New(apperr.Validation, "usage: feedauth <url> basic <username> <password>")
+		}
+		cred = feedCredential{Type: authBasic, Name: args[0], Secret: args[1]}
+	case authBearer:
+		if len(args) != 1 {
+			return apperr.New(apperr.Validation, "usage: feedauth <url> bearer <token>")
+		}
+		cred = feedCredential{Type: authBearer, Secret: args[0]}
+	case authQuery:
+		if len(args) != 2 {
+			return apperr.New(apperr.Validation, "usage: feedauth <url> query <param> <value>")
+		}
+		cred = feedCredential{Type: authQuery, Name: args[0], Secret: args[1]}
+	case "none":
+		_, err = s.dbq.DeleteFeedCredential(context.Background(), feed.ID)
+		if err != nil {
+			return apperr.WrapDB(err, "unable to remove credentials")
+		}
+		fmt.Printf("Credentials removed from %v\n", feed.Name)
+		return nil
+	default:
+		return apperr.New(apperr.Validation, "unknown auth type %v: expected basic, bearer, query or none", cmd.Arguments[1])
 	}
 
-	for _, pst := range posts {
-		fmt.Printf("\ntitle: %v\n", pst.Title)
-		fmt.Printf("--published at: %v\n", pst.PublishedAt)
-		fmt.Printf("--description: %v\n", pst.Description)
-		fmt.Printf("--url: %v\n", pst.Url)
+	err = s.saveFeedCredential(context.Background(), feed.ID, cred)
+	if err != nil {
+		return err
 	}
+
+	fmt.Printf("Stored %v credentials for %v\n", cred.Type, feed.Name)
 	return nil
 }
 
+func HandlerFilter(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) < 1 {
+		return apperr.New(apperr.Validation, "the filter handler takes a subcommand: add, list or rm")
+	}
+
+	switch cmd.Arguments[0] {
+	case "add":
+		return addFilter(s, cmd, user)
+	case "list":
+		return listFilters(s, user)
+	case "rm":
+		if len(cmd.Arguments) != 2 {
+			return apperr.New(apperr.Validation, "filter rm takes 1 argument: filter id")
+		}
+		return removeFilter(s, cmd.Arguments[1], user)
+	default:
+		return apperr.New(apperr.Validation, "unknown filter subcommand %q, expected add, list or rm", cmd.Arguments[0])
+	}
+}
+
 func HandlerFollow(s *State, cmd Command, user database.User) error {
 	if len(cmd.Arguments) < 1 {
-		return fmt.Errorf("the follow handler takes 1 argumane: url")
+		return apperr.New(apperr.Validation, "the follow handler takes 1 argumane: url")
 	}
 
 	//usr
 
 	feed, err := s.dbq.GetFeed(context.Background(), cmd.Arguments[0])
 	if err != nil {
-		return fmt.Errorf("unable to find feed: %v", err)
+		return apperr.WrapDB(err, "unable to find feed")
 	}
 
 	_, err = s.dbq.CreateFeedFollow(
@@ -129,7 +560,7 @@ func HandlerFollow(s *State, cmd Command, user database.User) error {
 			FeedID:    feed.ID,
 		})
 	if err != nil {
-		return fmt.Errorf("unable to follow feed: %v", err)
+		return apperr.WrapDB(err, "unable to follow feed")
 	}
 
 	fmt.Printf("Feed: %v, followed by %v\n", feed.Name, user.Name)
@@ -138,202 +569,1178 @@ func HandlerFollow(s *State, cmd Command, user database.User) error {
 
 func HandlerFollowing(s *State, cmd Command, user database.User) error {
 	if len(cmd.Arguments) > 0 {
-		return fmt.Errorf("the following handler takes no arguments")
+		return apperr.New(apperr.Validation, "the following handler takes no arguments")
 	}
 
 	//usr
 
-	feeds, err := s.dbq.GetFeedFollowsForUser(context.Background(), user.ID)
+	feeds, err := s.taggedFollows(context.Background(), user)
 	if err != nil {
-		return fmt.Errorf("unable to retrieve followed feeds: %v", err)
+		return err
 	}
 
-	fmt.Printf("Feeds followed by %v:\n", user.Name)
+	rows := make([]followRow, 0, len(feeds))
 	for _, feed := range feeds {
-		fmt.Printf(" -%v", feed.FeedName)
+		rows = append(rows, followRow{
+			Name:  feed.Name,
+			Url:   RedactURL(feed.Url),
+			Tags:  feed.Tags,
+			Notes: feed.Notes,
+		})
+	}
+
+	return s.render(rows, func() error {
+		// a feed with several tags is listed under each of its folders
+		folders := map[string][]followRow{}
+		for _, row := range rows {
+			if len(row.Tags) == 0 {
+				folders[""] = append(folders[""], row)
+			}
+			for _, tag := range row.Tags {
+				folders[tag] = append(folders[tag], row)
+			}
+		}
+		names := make([]string, 0, len(folders))
+		for name := range folders {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Printf("Feeds followed by %v:\n", user.Name)
+		for _, name := range names {
+			indent := ""
+			if name != "" {
+				fmt.Printf("%v/\n", name)
+				indent = "  "
+			}
+			for _, row := range folders[name] {
+				fmt.Printf("%v -%v (%v)\n", indent, row.Name, row.Url)
+				if row.Notes != "" {
+					fmt.Printf("%v    %v\n", indent, row.Notes)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func HandlerFullContent(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) != 2 {
+		return apperr.New(apperr.Validation, "the fullcontent handler takes 2 arguments: url, on|off")
+	}
+
+	var enable bool
+	switch cmd.Arguments[1] {
+	case "on":
+		enable = true
+	case "off":
+		enable = false
+	default:
+		return apperr.New(apperr.Validation, "expected on or off, got %v", cmd.Arguments[1])
+	}
+
+	feed, err := s.dbq.GetFeed(context.Background(), cmd.Arguments[0])
+	if err != nil {
+		return apperr.WrapDB(err, "unable to find feed")
+	}
+	if !canManageFeed(user, feed) {
+		return apperr.New(apperr.Permission, "only the user who added %v or an admin can change how it is fetched", feed.Name)
+	}
+
+	feed, err = s.dbq.SetFeedFullContent(
+		context.Background(),
+		database.SetFeedFullContentParams{
+			ID:               feed.ID,
+			FetchFullContent: enable,
+		})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to update feed")
 	}
+
+	fmt.Printf("Full content fetching for %v: %v\n", feed.Name, cmd.Arguments[1])
 	return nil
 }
 
-func HandlerGetFeeds(s *State, cmd Command) error {
+func HandlerGetFeeds(s *State, cmd Command, user database.User) error {
 	if len(cmd.Arguments) > 0 {
-		return fmt.Errorf("the feeds handler takes no arguments")
+		return apperr.New(apperr.Validation, "the feeds handler takes no arguments")
 	}
 
 	feeds, err := s.dbq.GetFeeds(context.Background())
 	if err != nil {
-		return fmt.Errorf("unable to retrieve feeds: %v", err)
+		return apperr.WrapDB(err, "unable to retrieve feeds")
 	}
 
+	rows := make([]feedRow, 0, len(feeds))
 	for _, feed := range feeds {
 		usrName, err := s.dbq.MatchUser(context.Background(), feed.UserID)
 		if err != nil {
-			return fmt.Errorf("unable to match user to feed: %v", err)
+			return apperr.WrapDB(err, "unable to match user to feed")
 		}
-		fmt.Printf("Feed: %v\n	-URL: %v\n	-User Name: %v\n", feed.Name, feed.Url, usrName)
+		rows = append(rows, feedRow{
+			Name:  feed.Name,
+			Url:   RedactURL(feed.Url),
+			Owner: usrName,
+		})
 	}
-	return nil
+
+	return s.render(rows, func() error {
+		for _, row := range rows {
+			fmt.Printf("Feed: %v\n	-URL: %v\n	-User Name: %v\n", row.Name, row.Url, row.Owner)
+		}
+		return nil
+	})
 }
 
-func HandlerGetUsers(s *State, cmd Command) error {
+func HandlerGetUsers(s *State, cmd Command, user database.User) error {
 	if len(cmd.Arguments) > 0 {
-		return fmt.Errorf("the users handler takes no arguments")
+		return apperr.New(apperr.Validation, "the users handler takes no arguments")
 	}
 
 	usrs, err := s.dbq.GetUsers(context.Background())
 	if err != nil {
-		return fmt.Errorf("unable to retrieve users: %v", err)
+		return apperr.WrapDB(err, "unable to retrieve users")
 	}
 
+	// only admins need to see who can do what
+	admin := hasRole(user, RoleAdmin)
+	rows := make([]userRow, 0, len(usrs))
 	for _, usr := range usrs {
-		if usr == s.point.Current_user_name {
-			fmt.Printf("%v (current)\n", usr)
-		} else {
-			fmt.Println(usr)
+		row := userRow{
+			Name:    usr.Name,
+			Current: usr.Name == s.point.Current_user_name,
 		}
+		if admin {
+			row.Role = usr.Role
+		}
+		rows = append(rows, row)
 	}
-	return nil
+
+	return s.render(rows, func() error {
+		for _, row := range rows {
+			line := row.Name
+			if row.Role != "" {
+				line += " - " + row.Role
+			}
+			if row.Current {
+				line += " (current)"
+			}
+			fmt.Println(line)
+		}
+		return nil
+	})
 }
 
-func HandlerLogin(s *State, cmd Command) error {
-	if len(cmd.Arguments) == 0 {
-		return fmt.Errorf("the login handler expects a single argument, the username")
+func HandlerGrant(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) != 2 {
+		return apperr.New(apperr.Validation, "the grant handler takes 2 arguments: user, role")
 	}
 
-	_, err := s.dbq.GetUser(context.Background(), cmd.Arguments[0])
+	role := strings.ToLower(cmd.Arguments[1])
+	err := validRole(role)
 	if err != nil {
-		return fmt.Errorf("unknown user: %v", err)
+		return err
 	}
 
-	err = s.point.SetUser(cmd.Arguments[0])
+	target, err := s.dbq.GetUser(context.Background(), cmd.Arguments[0])
 	if err != nil {
-		return fmt.Errorf("unable to set user via pointer: %v", err)
+		return apperr.WrapDB(err, "unknown user")
 	}
-	return nil
-}
 
-func HandlerRegister(s *State, cmd Command) error {
-	if len(cmd.Arguments) == 0 {
-		return fmt.Errorf("the register handler expects a single argument, a name")
+	if target.Role == RoleAdmin && role != RoleAdmin {
+		admins, err := s.dbq.CountAdmins(context.Background())
+		if err != nil {
+			return apperr.WrapDB(err, "unable to count admins")
+		}
+		if admins == 1 {
+			return apperr.New(apperr.Conflict, "%v is the only admin, grant someone else admin first", target.Name)
+		}
 	}
 
-	usr, err := s.dbq.CreateUser(
+	err = s.dbq.SetUserRole(
 		context.Background(),
-		database.CreateUserParams{
-			ID:        uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Name:      cmd.Arguments[0],
+		database.SetUserRoleParams{
+			ID:   target.ID,
+			Role: role,
 		})
 	if err != nil {
-		return fmt.Errorf("unable to register %v: %v", cmd.Arguments[0], err)
+		return apperr.WrapDB(err, "unable to change role")
 	}
 
-	err = s.point.SetUser(cmd.Arguments[0])
-	if err != nil {
-		return fmt.Errorf("unable to set user via pointer: %v", err)
-	}
-	fmt.Printf("user has been created: %v\n", usr.Name)
+	fmt.Printf("%v is now %v\n", target.Name, role)
 	return nil
 }
 
-func HandlerReset(s *State, cmd Command) error {
-	if len(cmd.Arguments) > 0 {
-		return errors.New("too many arguments, reset takes none")
+func HandlerImport(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) != 1 {
+		return apperr.New(apperr.Validation, "the import handler takes 1 argument: file")
 	}
 
-	err := s.dbq.ResetUsers(context.Background())
+	f, err := os.Open(cmd.Arguments[0])
 	if err != nil {
-		return fmt.Errorf("unable to reset users table: %v", err)
-	}
-	return nil
-}
-
-func HandlerUnfollow(s *State, cmd Command, user database.User) error {
-	if len(cmd.Arguments) < 1 {
-		return errors.New("unfollow handler takes 1 argument: feed URL")
+		return apperr.Wrap(err, "unable to open file")
 	}
+	defer f.Close()
 
-	_, err := s.dbq.Unfollow(context.Background(), database.UnfollowParams{
-		UserID: user.ID,
-		Url:    cmd.Arguments[0],
-	})
+	feeds, err := parseOPML(f)
 	if err != nil {
-		return fmt.Errorf("unable to unfollow feed: %v", err)
+		return err
 	}
 
-	return nil
-}
-
-func MiddlewareLoggedIn(handler func(s *State, cmd Command, user database.User) error) func(*State, Command) error {
-	return func(s *State, cmd Command) error {
-		usr, err := s.dbq.GetUser(context.Background(), s.point.Current_user_name)
+	added := 0
+	for _, feed := range feeds {
+		created, err := s.importFeed(context.Background(), user, feed)
 		if err != nil {
-			return fmt.Errorf("unable to find current user: %v", err)
+			name := feed.Name
+			if name == feed.Url {
+				name = RedactURL(name)
+			}
+			return apperr.Wrap(err, "%v", name)
+		}
+		if created {
+			added++
 		}
-		handler(s, cmd, usr)
-		return nil
 	}
+	fmt.Printf("Imported %v feeds, %v of them new\n", len(feeds), added)
+	return nil
 }
 
-// Registers new command into library
-func (cmds Commands) Register(name string, f func(*State, Command) error) {
-	cmds.Library[name] = f
-
-	_, exist := cmds.Library[name]
-	if !exist {
-		fmt.Printf("failed to add %v to commands\n", name)
+func HandlerLogin(s *State, cmd Command) error {
+	if len(cmd.Arguments) == 0 {
+		return apperr.New(apperr.Validation, "the login handler expects a single argument, the username")
 	}
-}
 
-func (cmds Commands) Run(s *State, cmd Command) error {
-	c, exist := cmds.Library[cmd.Name]
-	if !exist {
-		return fmt.Errorf("%v not found in commands", cmd.Name)
+	_, err := s.dbq.GetUser(context.Background(), cmd.Arguments[0])
+	if err != nil {
+		return apperr.WrapDB(err, "unknown user")
 	}
 
-	return c(s, cmd)
+	err = s.point.SetUser(cmd.Arguments[0])
+	if err != nil {
+		return apperr.Wrap(err, "unable to set user via pointer")
+	}
+	return nil
 }
 
-func scrapeFeeds(s *State) error {
-	feed, err := s.dbq.GetNextFeedToFetch(context.Background())
-	if err != nil {
-		return fmt.Errorf("unable to fetch next feed: %v", err)
+func HandlerLogout(s *State, cmd Command) error {
+	if len(cmd.Arguments) > 0 {
+		return apperr.New(apperr.Validation, "the logout handler takes no arguments")
+	}
+	if s.point.Current_user_name == "" {
+		return apperr.New(apperr.Permission, "not logged in")
 	}
 
-	feed, err = s.dbq.MarkFeedFetched(context.Background(), feed.ID)
+	err := s.point.SetUser("")
 	if err != nil {
-		return fmt.Errorf("unable to mark next feed: %v", err)
+		return apperr.Wrap(err, "unable to set user via pointer")
 	}
+	return nil
+}
 
-	items, err := s.FetchFeed(context.Background(), feed.Url)
-	if err != nil {
-		return fmt.Errorf("unable to list feed: %v", err)
+func HandlerMarkRead(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) < 1 {
+		return apperr.New(apperr.Validation, "the markread handler takes at least 1 argument: post id")
 	}
-	if items == nil {
-		return fmt.Errorf("items is %v", err)
+
+	for _, ref := range cmd.Arguments {
+		pst, err := s.findPost(context.Background(), user, ref)
+		if err != nil {
+			return err
+		}
+		err = s.dbq.MarkPostRead(
+			context.Background(),
+			database.MarkPostReadParams{
+				UserID: user.ID,
+				PostID: pst.ID,
+				ReadAt: sql.NullTime{Time: time.Now(), Valid: true},
+			})
+		if err != nil {
+			return apperr.WrapDB(err, "unable to mark post read")
+		}
 	}
+	return nil
+}
 
-	for _, itm := range items.Channel.Item {
-		t, err := time.Parse("Mon, 02 Jan 2006 15:04:05 -0700", itm.PubDate)
+func HandlerPrune(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) > 0 {
+		return apperr.New(apperr.Validation, "the prune handler takes no arguments, only flags")
+	}
+
+	opts, err := pruneOptionsFrom(s, cmd)
+	if err != nil {
+		return err
+	}
+	opts.dryRun = cmd.boolFlag("dry-run")
+
+	n, err := s.prunePosts(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+
+	// feeds left behind before unfollowing cleaned them up
+	var orphans []string
+	if opts.dryRun {
+		orphans, err = s.dbq.GetOrphanedFeeds(context.Background())
+	} else {
+		orphans, err = s.dbq.DeleteOrphanedFeeds(context.Background())
+	}
+	if err != nil {
+		return apperr.WrapDB(err, "unable to remove unfollowed feeds")
+	}
+	for _, name := range orphans {
+		fmt.Printf("%v: no followers\n", name)
+	}
+
+	if opts.dryRun {
+		fmt.Printf("%v posts and %v feeds would be deleted\n", n, len(orphans))
+	} else {
+		fmt.Printf("%v posts and %v feeds deleted\n", n, len(orphans))
+	}
+	return nil
+}
+
+func HandlerRead(s *State, cmd Command, user database.User) error {
+	args := cmd.Arguments
+	if len(args) != 1 {
+		return apperr.New(apperr.Validation, "the read handler takes 1 argument: post id")
+	}
+
+	pst, err := s.findPost(context.Background(), user, args[0])
+	if err != nil {
+		return err
+	}
+
+	if pst.Content == "" && cmd.boolFlag("fetch") && pst.Url != "" {
+		pst.Content, err = s.fetchArticle(context.Background(), pst.Url)
 		if err != nil {
-			return fmt.Errorf("unable to parse date: %v", err)
+			return err
 		}
-		_, err = s.dbq.CreatePost(
+		err = s.dbq.SetPostContent(
 			context.Background(),
-			database.CreatePostParams{
-				ID:          uuid.New(),
-				CreatedAt:   time.Now(),
-				UpdatedAt:   time.Now(),
-				Title:       itm.Title,
-				Url:         itm.Link,
-				Description: itm.Description,
-				PublishedAt: t,
-				FeedID:      feed.ID,
+			database.SetPostContentParams{
+				ID:      pst.ID,
+				Content: pst.Content,
+			})
+		if err != nil {
+			return apperr.WrapDB(err, "unable to save post content")
+		}
+	}
+
+	// best text available: the extracted article, the feed's full content, then the summary
+	body := pst.Content
+	if body == "" {
+		body = pst.ContentEncoded
+	}
+	if body == "" {
+		body = pst.Description
+	}
+
+	fmt.Printf("%v\n", pst.Title)
+	fmt.Printf("--published at: %v\n", pst.PublishedAt)
+	if pst.Author != "" {
+		fmt.Printf("--author: %v\n", pst.Author)
+	}
+	fmt.Printf("--url: %v\n\n", pst.Url)
+	fmt.Println(render.Text(body, render.Options{Width: terminalWidth()}))
+
+	err = s.dbq.MarkPostRead(
+		context.Background(),
+		database.MarkPostReadParams{
+			UserID: user.ID,
+			PostID: pst.ID,
+			ReadAt: sql.NullTime{Time: time.Now(), Valid: true},
+		})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to mark post read")
+	}
+	return nil
+}
+
+func HandlerRegister(s *State, cmd Command) error {
+	if len(cmd.Arguments) == 0 {
+		return apperr.New(apperr.Validation, "the register handler expects a single argument, a name")
+	}
+	// anyone can create the first user, after that only admins add users
+	usrs, err := s.dbq.GetUsers(context.Background())
+	if err != nil {
+		return apperr.WrapDB(err, "unable to retrieve users")
+	}
+	if len(usrs) > 0 {
+		_, err = requireRole(s, cmd, RoleAdmin)
+		if err != nil {
+			return err
+		}
+	}
+
+	usr, err := s.dbq.CreateUser(
+		context.Background(),
+		database.CreateUserParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      cmd.Arguments[0],
+		})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to register %v", cmd.Arguments[0])
+	}
+
+	err = s.point.SetUser(cmd.Arguments[0])
+	if err != nil {
+		return apperr.Wrap(err, "unable to set user via pointer")
+	}
+	fmt.Printf("user has been created: %v\n", usr.Name)
+	return nil
+}
+
+func HandlerRename(s *State, cmd Command, user database.User) error {
+	args := cmd.Arguments
+	reset := cmd.boolFlag("reset")
+	notesSet := cmd.hasFlag("notes")
+	if len(args) < 1 || (len(args) < 2 && !reset && !notesSet) {
+		return apperr.New(apperr.Validation, "the rename handler takes 2 arguments: url, name")
+	}
+	if len(args) > 1 && reset {
+		return apperr.New(apperr.Validation, "give either a name or --reset, not both")
+	}
+
+	follow, err := s.dbq.GetFeedFollow(
+		context.Background(),
+		database.GetFeedFollowParams{
+			UserID: user.ID,
+			Url:    args[0],
+		})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to find followed feed")
+	}
+
+	if len(args) > 1 || reset {
+		name := strings.TrimSpace(strings.Join(args[1:], " "))
+		err = s.dbq.RenameFeedFollow(
+			context.Background(),
+			database.RenameFeedFollowParams{
+				ID:          follow.ID,
+				DisplayName: name,
+			})
+		if err != nil {
+			return apperr.WrapDB(err, "unable to rename feed")
+		}
+	}
+	if notesSet {
+		err = s.dbq.SetFeedFollowNotes(
+			context.Background(),
+			database.SetFeedFollowNotesParams{
+				ID:    follow.ID,
+				Notes: strings.TrimSpace(cmd.stringFlag("notes")),
 			})
 		if err != nil {
-			return fmt.Errorf("unable to save post: %v", err)
+			return apperr.WrapDB(err, "unable to save notes")
+		}
+	}
+	return nil
+}
+
+func HandlerRenameUser(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) != 2 {
+		return apperr.New(apperr.Validation, "the renameuser handler takes 2 arguments: old name, new name")
+	}
+
+	target, err := s.dbq.GetUser(context.Background(), cmd.Arguments[0])
+	if err != nil {
+		return apperr.WrapDB(err, "unknown user")
+	}
+	if target.ID != user.ID && !hasRole(user, RoleAdmin) {
+		return apperr.New(apperr.Permission, "only admins can rename other users")
+	}
+
+	renamed, err := s.dbq.RenameUser(
+		context.Background(),
+		database.RenameUserParams{
+			ID:   target.ID,
+			Name: cmd.Arguments[1],
+		})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to rename user")
+	}
+
+	if target.Name == s.point.Current_user_name {
+		err = s.point.SetUser(renamed.Name)
+		if err != nil {
+			return apperr.Wrap(err, "unable to set user via pointer")
 		}
 	}
+	fmt.Printf("%v is now %v\n", target.Name, renamed.Name)
 	return nil
 }
+
+func HandlerReset(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) > 0 {
+		return apperr.New(apperr.Validation, "too many arguments, reset takes none")
+	}
+	if !cmd.boolFlag("yes") {
+		ok, err := confirm("Delete every user, feed and post?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("nothing was deleted")
+		}
+	}
+
+	err := s.dbq.ResetUsers(context.Background())
+	if err != nil {
+		return apperr.WrapDB(err, "unable to reset users table")
+	}
+	return s.point.SetUser("")
+}
+
+func HandlerRetention(s *State, cmd Command, user database.User) error {
+	args := cmd.Arguments
+	if len(args) != 1 {
+		return apperr.New(apperr.Validation, "the retention handler takes 1 argument: url")
+	}
+
+	feed, err := s.dbq.GetFeed(context.Background(), args[0])
+	if err != nil {
+		return apperr.WrapDB(err, "unable to find feed")
+	}
+
+	days, keep, forever := cmd.intFlag("days"), cmd.intFlag("keep"), cmd.boolFlag("forever")
+	if days >= 0 || keep >= 0 || forever {
+		if !canManageFeed(user, feed) {
+			return apperr.New(apperr.Permission, "only the user who added %v or an admin can change its retention", feed.Name)
+		}
+		// a stored 0 opts the feed out of the global policy
+		if forever {
+			feed.RetentionDays = sql.NullInt32{Valid: true}
+			feed.RetentionMaxPosts = sql.NullInt32{Valid: true}
+		}
+		if days >= 0 {
+			feed.RetentionDays = sql.NullInt32{Int32: int32(days), Valid: days > 0}
+		}
+		if keep >= 0 {
+			feed.RetentionMaxPosts = sql.NullInt32{Int32: int32(keep), Valid: keep > 0}
+		}
+		feed, err = s.dbq.SetFeedRetention(
+			context.Background(),
+			database.SetFeedRetentionParams{
+				ID:                feed.ID,
+				RetentionDays:     feed.RetentionDays,
+				RetentionMaxPosts: feed.RetentionMaxPosts,
+			})
+		if err != nil {
+			return apperr.WrapDB(err, "unable to update feed")
+		}
+	}
+
+	fmt.Printf("Retention for %v:\n", feed.Name)
+	if feed.RetentionDays.Valid && feed.RetentionMaxPosts.Valid &&
+		feed.RetentionDays.Int32 == 0 && feed.RetentionMaxPosts.Int32 == 0 {
+		fmt.Println("	-posts are kept forever")
+		return nil
+	}
+	if feed.RetentionDays.Valid && feed.RetentionDays.Int32 == 0 {
+		fmt.Println("	-days: no limit")
+	} else if feed.RetentionDays.Valid {
+		fmt.Printf("	-days: %v\n", feed.RetentionDays.Int32)
+	} else {
+		fmt.Printf("	-days: global (%v)\n", s.point.Retention_days)
+	}
+	if feed.RetentionMaxPosts.Valid && feed.RetentionMaxPosts.Int32 == 0 {
+		fmt.Println("	-newest posts kept: all")
+	} else if feed.RetentionMaxPosts.Valid {
+		fmt.Printf("	-newest posts kept: %v\n", feed.RetentionMaxPosts.Int32)
+	} else {
+		fmt.Printf("	-newest posts kept: global (%v)\n", s.point.Retention_max_posts)
+	}
+	return nil
+}
+
+func addFilter(s *State, cmd Command, user database.User) error {
+	feedURL := cmd.stringFlag("feed")
+	title := cmd.stringFlag("title-regex")
+	author := cmd.stringFlag("author-regex")
+	keyword := cmd.stringFlag("keyword")
+	action := cmd.stringFlag("action")
+	if len(cmd.Arguments) > 1 {
+		return apperr.New(apperr.Validation, "filter add takes no arguments, only flags")
+	}
+	if title == "" && author == "" && keyword == "" {
+		return apperr.New(apperr.Validation, "filter add needs at least one of --title-regex, --author-regex or --keyword")
+	}
+	switch action {
+	case actionHide, actionStar, actionMarkRead:
+	default:
+		return apperr.New(apperr.Validation, "--action must be hide, star or markread")
+	}
+
+	rule := database.CreateFilterRuleParams{
+		ID:            uuid.New(),
+		CreatedAt:     time.Now(),
+		UserID:        user.ID,
+		TitlePattern:  title,
+		AuthorPattern: author,
+		Keyword:       keyword,
+		Action:        action,
+	}
+	// catch bad patterns now rather than every time posts are listed
+	_, err := compileRule(database.FilterRule{TitlePattern: rule.TitlePattern, AuthorPattern: rule.AuthorPattern})
+	if err != nil {
+		return err
+	}
+	if feedURL != "" {
+		feed, err := s.dbq.GetFeed(context.Background(), feedURL)
+		if err != nil {
+			return apperr.WrapDB(err, "unable to find feed")
+		}
+		rule.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	created, err := s.dbq.CreateFilterRule(context.Background(), rule)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to create filter")
+	}
+	fmt.Printf("Filter %v added\n", created.ID.String()[:8])
+	return nil
+}
+
+func listFilters(s *State, user database.User) error {
+	rules, err := s.dbq.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to get filters")
+	}
+	out := make([]filterRow, 0, len(rules))
+	for _, r := range rules {
+		row := filterRow{
+			ID:          r.ID.String()[:8],
+			Action:      r.Action,
+			TitleRegex:  r.TitlePattern,
+			AuthorRegex: r.AuthorPattern,
+			Keyword:     r.Keyword,
+		}
+		if r.FeedUrl.Valid {
+			row.Feed = RedactURL(r.FeedUrl.String)
+		}
+		out = append(out, row)
+	}
+
+	return s.render(out, func() error {
+		if len(out) == 0 {
+			fmt.Println("No filters")
+			return nil
+		}
+		for _, row := range out {
+			scope := row.Feed
+			if scope == "" {
+				scope = "all feeds"
+			}
+			rule := filterRule{FilterRule: database.FilterRule{
+				TitlePattern:  row.TitleRegex,
+				AuthorPattern: row.AuthorRegex,
+				Keyword:       row.Keyword,
+			}}
+			fmt.Printf(" -%v: %v %v on %v\n", row.ID, row.Action, rule.describe(), scope)
+		}
+		return nil
+	})
+}
+
+func removeFilter(s *State, ref string, user database.User) error {
+	// a short prefix could pick a rule the user didn't mean
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if len(ref) < 8 {
+		return apperr.New(apperr.Validation, "filter id %q is too short: use at least 8 characters", ref)
+	}
+
+	rules, err := s.dbq.GetFilterRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to get filters")
+	}
+
+	var matched []uuid.UUID
+	for _, r := range rules {
+		if strings.HasPrefix(r.ID.String(), ref) {
+			matched = append(matched, r.ID)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return apperr.New(apperr.NotFound, "no filter with id %v", ref)
+	case 1:
+	default:
+		return apperr.New(apperr.Validation, "more than one filter starts with %v", ref)
+	}
+
+	err = s.dbq.DeleteFilterRule(context.Background(), matched[0])
+	if err != nil {
+		return apperr.WrapDB(err, "unable to delete filter")
+	}
+	fmt.Printf("Filter %v removed\n", matched[0].String()[:8])
+	return nil
+}
+
+func HandlerRmFeed(s *State, cmd Command, user database.User) error {
+	args := cmd.Arguments
+	if len(args) != 1 {
+		return apperr.New(apperr.Validation, "the rmfeed handler takes 1 argument: url")
+	}
+
+	feed, err := s.dbq.GetFeed(context.Background(), args[0])
+	if err != nil {
+		return apperr.WrapDB(err, "unable to find feed")
+	}
+	if !canManageFeed(user, feed) {
+		return apperr.New(apperr.Permission, "only the user who added %v or an admin can remove it", feed.Name)
+	}
+
+	if !cmd.boolFlag("yes") {
+		usage, err := s.dbq.GetFeedUsage(context.Background(), feed.ID)
+		if err != nil {
+			return apperr.WrapDB(err, "unable to count feed posts")
+		}
+		ok, err := confirm(fmt.Sprintf(
+			"Remove %v along with its %v posts and %v follows?", feed.Name, usage.Posts, usage.Followers))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("feed not removed")
+		}
+	}
+
+	err = s.dbq.DeleteFeed(context.Background(), feed.ID)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to remove feed")
+	}
+
+	fmt.Printf("Removed %v\n", feed.Name)
+	return nil
+}
+
+func HandlerStar(s *State, cmd Command, user database.User) error {
+	return setStarred(s, cmd, user, true)
+}
+
+func HandlerStats(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) > 1 {
+		return apperr.New(apperr.Validation, "the stats handler takes at most 1 argument: time window\nEx: '24h'")
+	}
+
+	since := time.Time{}
+	if len(cmd.Arguments) == 1 {
+		dur, err := time.ParseDuration(cmd.Arguments[0])
+		if err != nil {
+			return apperr.WrapKind(apperr.Validation, err, "unable to parse duration")
+		}
+		since = time.Now().Add(-dur)
+	}
+
+	rows, err := s.dbq.GetFeedBandwidthStats(context.Background(), since)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to retrieve stats")
+	}
+
+	out := make([]feedStatsRow, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, feedStatsRow{
+			Feed:         row.Name,
+			Fetches:      row.Fetches,
+			WireBytes:    row.WireBytes,
+			DecodedBytes: row.DecodedBytes,
+		})
+	}
+
+	return s.render(out, func() error {
+		var totalWire, totalDecoded, totalFetches int64
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FEED\tFETCHES\tON WIRE\tDECODED\tSAVED\tPER FETCH")
+		for _, row := range out {
+			perFetch := int64(0)
+			if row.Fetches > 0 {
+				perFetch = row.WireBytes / row.Fetches
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+				row.Feed, row.Fetches, formatBytes(row.WireBytes), formatBytes(row.DecodedBytes),
+				savedPercent(row.WireBytes, row.DecodedBytes), formatBytes(perFetch))
+			totalWire += row.WireBytes
+			totalDecoded += row.DecodedBytes
+			totalFetches += row.Fetches
+		}
+		fmt.Fprintf(w, "TOTAL\t%v\t%v\t%v\t%v\t\n",
+			totalFetches, formatBytes(totalWire), formatBytes(totalDecoded), savedPercent(totalWire, totalDecoded))
+		return w.Flush()
+	})
+}
+
+func HandlerUnstar(s *State, cmd Command, user database.User) error {
+	return setStarred(s, cmd, user, false)
+}
+
+func HandlerTag(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) < 2 {
+		return apperr.New(apperr.Validation, "the tag handler takes at least 2 arguments: url, tag")
+	}
+
+	follow, err := s.dbq.GetFeedFollow(
+		context.Background(),
+		database.GetFeedFollowParams{
+			UserID: user.ID,
+			Url:    cmd.Arguments[0],
+		})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to find followed feed")
+	}
+
+	for _, raw := range cmd.Arguments[1:] {
+		tag, err := normalizeTag(raw)
+		if err != nil {
+			return err
+		}
+		err = s.dbq.AddFollowTag(
+			context.Background(),
+			database.AddFollowTagParams{
+				FeedFollowID: follow.ID,
+				Name:         tag,
+			})
+		if err != nil {
+			return apperr.WrapDB(err, "unable to tag feed")
+		}
+	}
+	return nil
+}
+
+func HandlerUntag(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) != 2 {
+		return apperr.New(apperr.Validation, "the untag handler takes 2 arguments: url, tag")
+	}
+
+	follow, err := s.dbq.GetFeedFollow(
+		context.Background(),
+		database.GetFeedFollowParams{
+			UserID: user.ID,
+			Url:    cmd.Arguments[0],
+		})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to find followed feed")
+	}
+
+	tag, err := normalizeTag(cmd.Arguments[1])
+	if err != nil {
+		return err
+	}
+	n, err := s.dbq.RemoveFollowTag(
+		context.Background(),
+		database.RemoveFollowTagParams{
+			FeedFollowID: follow.ID,
+			Name:         tag,
+		})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to remove tag")
+	}
+	if n == 0 {
+		return apperr.New(apperr.NotFound, "feed isn't tagged %v", tag)
+	}
+	return nil
+}
+
+func HandlerUnfollow(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) < 1 {
+		return apperr.New(apperr.Validation, "unfollow handler takes 1 argument: feed URL")
+	}
+
+	// the last follower leaving removes the feed, and with its posts go the
+	// user's stars and download history
+	if !cmd.boolFlag("yes") {
+		ok, err := confirmUnfollow(s, cmd.Arguments[0], user)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("still following the feed")
+		}
+	}
+
+	follow, err := s.dbq.Unfollow(context.Background(), database.UnfollowParams{
+		UserID: user.ID,
+		Url:    cmd.Arguments[0],
+	})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to unfollow feed")
+	}
+
+	// nobody reads a feed without followers, so it and its posts go too
+	n, err := s.dbq.DeleteFeedIfOrphaned(context.Background(), follow.FeedID)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to remove unfollowed feed")
+	}
+	if n > 0 {
+		fmt.Println("Feed had no other followers and was removed")
+	}
+	return nil
+}
+
+// Asks before unfollowing a feed nobody else follows when the user starred or
+// downloaded some of its posts
+func confirmUnfollow(s *State, url string, user database.User) (bool, error) {
+	ctx := context.Background()
+	feed, err := s.dbq.GetFeed(ctx, url)
+	if err != nil {
+		return false, apperr.WrapDB(err, "unable to find feed")
+	}
+	usage, err := s.dbq.GetFeedUsage(ctx, feed.ID)
+	if err != nil {
+		return false, apperr.WrapDB(err, "unable to count followers")
+	}
+	if usage.Followers > 1 {
+		return true, nil
+	}
+	saved, err := s.dbq.GetSavedPostCounts(ctx, database.GetSavedPostCountsParams{
+		FeedID: feed.ID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return false, apperr.WrapDB(err, "unable to count starred posts")
+	}
+	if saved.Starred == 0 && saved.Downloads == 0 {
+		return true, nil
+	}
+	var lost []string
+	if saved.Starred > 0 {
+		lost = append(lost, fmt.Sprintf("%v starred posts", saved.Starred))
+	}
+	if saved.Downloads > 0 {
+		lost = append(lost, fmt.Sprintf("the record of %v downloaded episodes", saved.Downloads))
+	}
+	return confirm(fmt.Sprintf("Nobody else follows %v, unfollowing removes it with your %v. Unfollow?",
+		feed.Name, strings.Join(lost, " and ")))
+}
+
+// Short summary of a post's state for listings
+func postFlags(row postRow) string {
+	var flags []string
+	if row.Starred {
+		flags = append(flags, "starred")
+	}
+	if row.Read {
+		flags = append(flags, "read")
+	}
+	if row.Hidden {
+		flags = append(flags, "hidden")
+	}
+	return strings.Join(flags, ", ")
+}
+
+func setStarred(s *State, cmd Command, user database.User, starred bool) error {
+	if len(cmd.Arguments) < 1 {
+		return apperr.New(apperr.Validation, "the %v handler takes at least 1 argument: post id", cmd.Name)
+	}
+
+	for _, ref := range cmd.Arguments {
+		pst, err := s.findPost(context.Background(), user, ref)
+		if err != nil {
+			return err
+		}
+		err = s.dbq.SetPostStarred(
+			context.Background(),
+			database.SetPostStarredParams{
+				UserID:  user.ID,
+				PostID:  pst.ID,
+				Starred: starred,
+			})
+		if err != nil {
+			return apperr.WrapDB(err, "unable to update post")
+		}
+	}
+	return nil
+}
+
+func HandlerWhoAmI(s *State, cmd Command, user database.User) error {
+	if len(cmd.Arguments) > 0 {
+		return apperr.New(apperr.Validation, "the whoami handler takes no arguments")
+	}
+
+	fmt.Printf("%v (%v)\n", user.Name, user.Role)
+	fmt.Printf("--registered: %v\n", user.CreatedAt.Format(time.DateOnly))
+	return nil
+}
+
+func MiddlewareLoggedIn(handler func(s *State, cmd Command, user database.User) error) func(*State, Command) error {
+	return MiddlewareRole(RoleReadOnly, handler)
+}
+
+// Like MiddlewareLoggedIn, but the current user also needs at least the given role
+func MiddlewareRole(role string, handler func(s *State, cmd Command, user database.User) error) func(*State, Command) error {
+	return func(s *State, cmd Command) error {
+		usr, err := requireRole(s, cmd, role)
+		if err != nil {
+			return err
+		}
+		return handler(s, cmd, usr)
+	}
+}
+
+// The current user, as long as they have at least the role cmd needs
+func requireRole(s *State, cmd Command, role string) (database.User, error) {
+	if s.point.Current_user_name == "" {
+		return database.User{}, apperr.New(apperr.Permission, "not logged in, use login or register first")
+	}
+	usr, err := s.dbq.GetUser(context.Background(), s.point.Current_user_name)
+	if err != nil {
+		return database.User{}, apperr.WrapDB(err, "unable to find current user")
+	}
+	if !hasRole(usr, role) {
+		return database.User{}, apperr.New(apperr.Permission, "%v needs the %v role, %v is %v", cmd.Name, role, usr.Name, usr.Role)
+	}
+	return usr, nil
+}
+
+// Marks the next feed as fetched and returns it, false when there are no feeds
+func nextFeed(ctx context.Context, s *State) (database.Feed, bool, error) {
+	feed, err := s.store.GetNextFeedToFetch(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.log.Info("no feeds to fetch")
+		return database.Feed{}, false, nil
+	}
+	if err != nil {
+		return database.Feed{}, false, apperr.WrapDB(err, "unable to fetch next feed")
+	}
+
+	feed, err = s.store.MarkFeedFetched(ctx, feed.ID)
+	if err != nil {
+		return database.Feed{}, false, apperr.WrapDB(err, "unable to mark next feed")
+	}
+	return feed, true, nil
+}
+
+// Fetches one feed and stores its new posts, failures are logged
+// along with the feed, saved counts the posts stored before any failure,
+// cancelling ctx abandons the requests still in flight
+func scrapeFeed(ctx context.Context, s *State, feed database.Feed) (saved int, err error) {
+	log := s.log.With("feed_id", feed.ID, "url", RedactURL(feed.Url))
+	log.Debug("fetching feed")
+	started := s.clock.Now()
+	defer func() {
+		if err != nil {
+			log.Error("scrape failed", "duration", s.clock.Now().Sub(started), "err", err)
+		}
+	}()
+
+	items, stats, fetchErr := s.FetchFeed(ctx, feed.Url)
+	defer func() {
+		s.metrics.observeFetch(stats, s.clock.Now().Sub(started))
+	}()
+	if stats.StatusCode != 0 {
+		err := s.store.CreateFeedFetch(
+			ctx,
+			database.CreateFeedFetchParams{
+				ID:              uuid.New(),
+				FeedID:          feed.ID,
+				FetchedAt:       s.clock.Now(),
+				StatusCode:      int32(stats.StatusCode),
+				ContentEncoding: stats.Encoding,
+				WireBytes:       stats.WireBytes,
+				DecodedBytes:    stats.DecodedBytes,
+			})
+		if err != nil {
+			return saved, apperr.WrapDB(err, "unable to record fetch")
+		}
+	}
+	log = log.With("status", stats.StatusCode)
+	if fetchErr != nil {
+		return saved, apperr.Wrap(fetchErr, "unable to list feed %v", feed.Name)
+	}
+	if items == nil {
+		return saved, apperr.New(apperr.Network, "no items found in %v", feed.Name)
+	}
+	log.Debug("parsed feed",
+		"items", len(items.Channel.Item),
+		"wire_bytes", stats.WireBytes,
+		"decoded_bytes", stats.DecodedBytes)
+
+	stored, err := s.store.GetFilterRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		return saved, apperr.WrapDB(err, "unable to get filters")
+	}
+	filters := compileRules(log, stored)
+
+	for _, itm := range items.Channel.Item {
+		// one odd date shouldn't cost the rest of the feed, the post is dated when we saw it
+		t, ok := itm.Published()
+		if !ok {
+			t = s.clock.Now()
+			stats.ParseFailed = true
+			log.Warn("unable to parse date, using the fetch time", "title", itm.Title, "pub_date", itm.PubDate)
+		}
+		post, err := s.store.CreatePost(
+			ctx,
+			database.CreatePostParams{
+				ID:              uuid.New(),
+				CreatedAt:       s.clock.Now(),
+				UpdatedAt:       s.clock.Now(),
+				Title:           itm.Title,
+				Url:             itm.Link,
+				Description:     itm.Description,
+				PublishedAt:     t,
+				FeedID:          feed.ID,
+				Guid:            strings.TrimSpace(itm.GUID.Value),
+				GuidIsPermalink: itm.GUID.PermaLink(),
+				Author:          itm.AuthorName(),
+				ContentEncoded:  itm.ContentEncoded,
+				Comments:        itm.Comments,
+				IdentityKey:     itemIdentity(itm),
+				LinkKey:         stripTracking(itm.Link),
+			})
+		if errors.Is(err, sql.ErrNoRows) {
+			// already stored from an earlier fetch
+			continue
+		}
+		if err != nil {
+			return saved, apperr.WrapDB(err, "unable to save post")
+		}
+		saved++
+		s.metrics.postsInserted.Inc()
+		log.Debug("stored post", "post_id", post.ID, "title", post.Title)
+
+		err = s.savePostMetadata(ctx, post.ID, itm)
+		if err != nil {
+			return saved, err
+		}
+
+		for _, f := range filters {
+			if !f.matches(post) {
+				continue
+			}
+			err = s.applyFilter(ctx, f, post.ID)
+			if err != nil {
+				return saved, err
+			}
+			log.Debug("applied filter", "post_id", post.ID, "filter_id", f.ID, "action", f.Action)
+		}
+
+		if feed.FetchFullContent && post.Url != "" {
+			// a page that can't be read only costs us the full text, not the post
+			content, err := s.fetchArticle(ctx, post.Url)
+			if err != nil {
+				log.Warn("unable to get full content", "post_id", post.ID, "post_url", RedactURL(post.Url), "err", err)
+				continue
+			}
+			err = s.store.SetPostContent(
+				ctx,
+				database.SetPostContentParams{
+					ID:      post.ID,
+					Content: content,
+				})
+			if err != nil {
+				return saved, apperr.WrapDB(err, "unable to save post content")
+			}
+		}
+	}
+
+	// agg and the daemon log each feed they fetch, one-off commands print their own summary
+	log.Debug("fetched feed",
+		"items", len(items.Channel.Item),
+		"new", saved,
+		"encoding", stats.Encoding,
+		"wire_bytes", stats.WireBytes,
+		"duration", s.clock.Now().Sub(started))
+	return saved, nil
+}
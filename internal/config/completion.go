@@ -0,0 +1,271 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+)
+
+// Hidden command the completion scripts call with the words typed so far,
+// the last one being the word under the cursor
+const completeCommand = "__complete"
+
+const bashCompletion = `# bash completion for gator
+_gator() {
+    local line=${COMP_LINE:0:COMP_POINT}
+    local -a words
+    read -ra words <<< "$line"
+    [[ $line == *[[:space:]] ]] && words+=("")
+    local cur=${words[${#words[@]}-1]}
+
+    local IFS=$'\n'
+    COMPREPLY=($(gator __complete "${words[@]:1}" 2>/dev/null))
+
+    # bash splits urls on : and =, only complete what comes after the last one
+    if [[ $cur == *[:=]* ]]; then
+        local prefix=${cur%"${cur##*[:=]}"}
+        COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+    fi
+}
+complete -o default -F _gator gator
+`
+
+const zshCompletion = `#compdef gator
+
+_gator() {
+    local -a candidates
+    candidates=("${(@f)$(gator __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    if (( ${#candidates} )); then
+        compadd -- "${candidates[@]}"
+    else
+        _files
+    fi
+}
+
+if [[ "$funcstack[1]" == "_gator" ]]; then
+    _gator "$@"
+else
+    compdef _gator gator
+fi
+`
+
+const fishCompletion = `# fish completion for gator
+function __gator_complete
+    set -l tokens (commandline -opc)
+    set -l results (gator __complete $tokens[2..-1] (commandline -ct) 2>/dev/null)
+    if test (count $results) -gt 0
+        printf '%s\n' $results
+    else
+        __fish_complete_path (commandline -ct)
+    end
+end
+complete -c gator -f -a '(__gator_complete)'
+`
+
+func HandlerCompletion(s *State, cmd Command) error {
+	if len(cmd.Arguments) != 1 {
+		return apperr.New(apperr.Validation, "the completion handler takes 1 argument: bash, zsh or fish")
+	}
+
+	var script string
+	switch cmd.Arguments[0] {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return apperr.New(apperr.Validation, "unknown shell %v: expected bash, zsh or fish", cmd.Arguments[0])
+	}
+	_, err := os.Stdout.WriteString(script)
+	if err != nil {
+		return apperr.Wrap(err, "unable to print completion script")
+	}
+	return nil
+}
+
+// Prints the candidates for the last word, one per line
+func (cmds Commands) complete(s *State, cmd Command) error {
+	words := cmd.Arguments
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+	for _, c := range cmds.candidates(s, words[:len(words)-1], cur) {
+		if strings.HasPrefix(c, cur) {
+			fmt.Println(c)
+		}
+	}
+	return nil
+}
+
+func (cmds Commands) candidates(s *State, before []string, cur string) []string {
+	if n := len(before); n > 0 && before[n-1] == "--output" {
+		return outputFormats()
+	}
+	var words []string
+	for i := 0; i < len(before); i++ {
+		switch {
+		case before[i] == "--output":
+			i++
+		case !strings.HasPrefix(before[i], "--output="):
+			words = append(words, before[i])
+		}
+	}
+
+	if len(words) == 0 {
+		if strings.HasPrefix(cur, "-") {
+			return []string{"--debug", "--help", "--output"}
+		}
+		return cmds.names()
+	}
+	spec, exist := cmds.Library[words[0]]
+	if !exist {
+		return nil
+	}
+
+	// find which positional argument the cursor is on, or the flag it's a value for
+	fs := spec.flagSet(s)
+	pos := 0
+	var pending *flag.Flag
+	for _, w := range words[1:] {
+		if pending != nil {
+			pending = nil
+			continue
+		}
+		if strings.HasPrefix(w, "-") && w != "-" {
+			name, _, hasValue := strings.Cut(strings.TrimLeft(w, "-"), "=")
+			if f := fs.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) {
+				pending = f
+			}
+			continue
+		}
+		pos++
+	}
+	if pending != nil {
+		return cmds.argValues(s, spec.FlagArgs[pending.Name])
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		flags := []string{"--debug", "--help", "--output"}
+		fs.VisitAll(func(f *flag.Flag) {
+			if len(f.Name) == 1 {
+				flags = append(flags, "-"+f.Name)
+			} else {
+				flags = append(flags, "--"+f.Name)
+			}
+		})
+		return flags
+	}
+
+	if len(spec.Args) == 0 {
+		return nil
+	}
+	last := spec.Args[len(spec.Args)-1]
+	if pos >= len(spec.Args) {
+		if !last.Repeated {
+			return nil
+		}
+		pos = len(spec.Args) - 1
+	}
+	return cmds.argValues(s, spec.Args[pos])
+}
+
+func (cmds Commands) names() []string {
+	var names []string
+	for _, spec := range cmds.visible() {
+		names = append(names, spec.Name)
+	}
+	return names
+}
+
+// Values an argument can take, looked up in the database where needed,
+// errors just mean nothing to complete
+func (cmds Commands) argValues(s *State, arg Arg) []string {
+	ctx := context.Background()
+	var values []string
+	switch arg.Kind {
+	case argChoice:
+		return arg.Choices
+	case argCommand:
+		return cmds.names()
+	case argFeed:
+		feeds, err := s.dbq.GetFeeds(ctx)
+		if err != nil {
+			return nil
+		}
+		for _, f := range feeds {
+			values = append(values, f.Url)
+		}
+	case argUser:
+		users, err := s.dbq.GetUsers(ctx)
+		if err != nil {
+			return nil
+		}
+		for _, u := range users {
+			values = append(values, u.Name)
+		}
+	case argFollowed, argTag, argPost, argFilter:
+		user, err := s.dbq.GetUser(ctx, s.point.Current_user_name)
+		if err != nil {
+			return nil
+		}
+		values = userValues(ctx, s, user, arg.Kind)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func userValues(ctx context.Context, s *State, user database.User, kind ArgKind) []string {
+	var values []string
+	switch kind {
+	case argFollowed:
+		follows, err := s.dbq.GetFeedFollowsForUser(ctx, user.ID)
+		if err != nil {
+			return nil
+		}
+		for _, f := range follows {
+			values = append(values, f.FeedUrl)
+		}
+	case argTag:
+		tags, err := s.dbq.GetFollowTagsForUser(ctx, user.ID)
+		if err != nil {
+			return nil
+		}
+		seen := map[string]bool{}
+		for _, t := range tags {
+			if !seen[t.Name] {
+				seen[t.Name] = true
+				values = append(values, t.Name)
+			}
+		}
+	case argPost:
+		posts, err := s.dbq.GetPostsForUser(ctx, database.GetPostsForUserParams{
+			UserID: user.ID,
+			Limit:  50,
+		})
+		if err != nil {
+			return nil
+		}
+		for _, p := range posts {
+			values = append(values, p.Post.ID.String())
+		}
+	case argFilter:
+		rules, err := s.dbq.GetFilterRulesForUser(ctx, user.ID)
+		if err != nil {
+			return nil
+		}
+		for _, r := range rules {
+			values = append(values, r.ID.String())
+		}
+	}
+	return values
+}
@@ -1,13 +1,19 @@
 package config
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"time"
 
+	"github.com/ScooballyD/gator/internal/apperr"
 	"github.com/ScooballyD/gator/internal/database"
+	"github.com/ScooballyD/gator/internal/logging"
 )
 
 const configFileName = ".gatorconfig.json"
@@ -15,78 +21,175 @@ const configFileName = ".gatorconfig.json"
 type Config struct {
 	Db_url            string `json:"db_url"`
 	Current_user_name string `json:"current_user_name"`
+	Credential_key    string `json:"credential_key,omitempty"`
+	Max_feed_bytes    int64  `json:"max_feed_bytes,omitempty"`
+	Download_dir      string `json:"download_dir,omitempty"`
+	Output            string `json:"output,omitempty"`
+
+	Retention_days      int `json:"retention_days,omitempty"`
+	Retention_max_posts int `json:"retention_max_posts,omitempty"`
+	// Unread posts are kept unless this is set to false
+	Retention_keep_unread *bool `json:"retention_keep_unread,omitempty"`
+
+	Log_level     string `json:"log_level,omitempty"`
+	Log_format    string `json:"log_format,omitempty"`
+	Log_file      string `json:"log_file,omitempty"`
+	Log_max_bytes int64  `json:"log_max_bytes,omitempty"`
+	Log_max_files int    `json:"log_max_files,omitempty"`
+
+	Daemon_dir string `json:"daemon_dir,omitempty"`
 }
 
 type State struct {
-	dbq   *database.Queries
-	point *Config
+	db      *sql.DB
+	backend backend
+	dbq     database.Querier
+	// What the scraper uses in place of dbq, http.DefaultClient and time.Now
+	store   feedStore
+	http    fetcher
+	clock   clock
+	point   *Config
+	output  string
+	log     *slog.Logger
+	logFile io.Closer
+	metrics *aggMetrics
+	// Set by --debug after the command name
+	debug bool
 }
 
-func (cfg Config) NewState() (State, error) {
+// Opens the database, with checkSchemaVersion it also makes sure the schema is
+// at the version gator was built for
+func (cfg Config) NewState(checkSchemaVersion bool) (State, error) {
 	s := State{
-		point: &cfg,
+		point:   &cfg,
+		output:  formatText,
+		metrics: newAggMetrics(),
+	}
+	if cfg.Output != "" {
+		err := s.SetOutput(cfg.Output)
+		if err != nil {
+			return State{}, apperr.WrapKind(apperr.Validation, err, "invalid output in config")
+		}
 	}
 
 	if s.point == nil {
 		return State{}, errors.New("failed to create new state")
 	}
 
-	db, err := sql.Open("postgres", cfg.Db_url)
+	// the log file stays open until gator exits
+	err := s.setLogger(cfg)
+	if err != nil {
+		return State{}, err
+	}
+
+	db, b, err := openDB(cfg.Db_url)
 	if err != nil {
-		return State{}, fmt.Errorf("failed to open database: %v", err)
+		return State{}, err
+	}
+	s.db, s.backend = db, b
+	if checkSchemaVersion {
+		err = checkSchema(context.Background(), &s)
+		if err != nil {
+			return State{}, err
+		}
 	}
-	dbQueries := database.New(db)
+	dbQueries := b.queries(timedDB{
+		db: db,
+		observe: func(query string, d time.Duration) {
+			s.metrics.querySeconds.Observe(d.Seconds(), query)
+		},
+	})
 	s.dbq = dbQueries
 	if s.dbq == nil {
 		return State{}, errors.New("failed to assign dbQueries to state")
 	}
+	s.store = dbQueries
+	s.http = newHTTPClient()
+	s.clock = systemClock{}
 
 	return s, nil
 }
 
+// Client for feeds, articles and enclosures, a server that never answers
+// fails the request instead of holding up agg or the daemon forever
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+	return &http.Client{Transport: transport}
+}
+
+// Replaces the logger with one built from the log settings of cfg,
+// closing the previous log file
+func (s *State) setLogger(cfg Config) error {
+	logger, closer, err := logging.New(logging.Options{
+		Level:    cfg.Log_level,
+		Format:   cfg.Log_format,
+		File:     cfg.Log_file,
+		MaxBytes: cfg.Log_max_bytes,
+		MaxFiles: cfg.Log_max_files,
+	})
+	if err != nil {
+		return apperr.WrapKind(apperr.Validation, err, "invalid logging in config")
+	}
+	if s.logFile != nil {
+		s.logFile.Close()
+	}
+	s.log, s.logFile = logger, closer
+	return nil
+}
+
+// Location of the config file, "~/.gatorconfig.json"
+func configPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", apperr.Wrap(err, "unable to find home dir")
+	}
+	return home + "/" + configFileName, nil
+}
+
 // Creates Config struct from "~/.gatorconfig.json"
 func Read() (Config, error) {
-	home, err := os.UserHomeDir()
+	path, err := configPath()
 	if err != nil {
 		//fmt.Println("Unable to find home dir : ", err)
-		return Config{}, fmt.Errorf("unable to find home dir: %v", err)
+		return Config{}, err
 	}
 
-	path := home + "/" + configFileName
-
 	jfile, err := os.ReadFile(path)
 	if err != nil {
 		//fmt.Println("Failed to access 'gatorconfig.json' : ", err)
-		return Config{}, fmt.Errorf("failed to access 'gatorconfig.json': %v", err)
+		return Config{}, apperr.Wrap(err, "failed to access 'gatorconfig.json'")
 	}
 
 	newConfig := Config{}
 	err = json.Unmarshal(jfile, &newConfig)
 	if err != nil {
 		//fmt.Println("Unmarshal error : ", err)
-		return Config{}, fmt.Errorf("unmarshal error: %v", err)
+		return Config{}, apperr.WrapKind(apperr.Validation, err, "unmarshal error")
 	}
 
 	return newConfig, nil
 }
 
+func (cfg Config) keepUnread() bool {
+	return cfg.Retention_keep_unread == nil || *cfg.Retention_keep_unread
+}
+
 func (cfg Config) SetUser(user string) error {
 	cfg.Current_user_name = user
 
 	jData, err := json.Marshal(cfg)
 	if err != nil {
-		return fmt.Errorf("marshal error: %v", err)
+		return apperr.Wrap(err, "marshal error")
 	}
 
-	home, err := os.UserHomeDir()
+	path, err := configPath()
 	if err != nil {
-		return fmt.Errorf("unable to find home dir: %v", err)
+		return err
 	}
-
-	path := home + "/" + configFileName
 	err = os.WriteFile(path, jData, 0666)
 	if err != nil {
-		return fmt.Errorf("write error: %v", err)
+		return apperr.Wrap(err, "write error")
 	}
 
 	return nil
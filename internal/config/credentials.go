@@ -0,0 +1,259 @@
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const credentialKeyEnv = "GATOR_CREDENTIAL_KEY"
+
+// Bytes in a credential key, AES-256 takes 32
+const credentialKeySize = 32
+
+const (
+	authBasic  = "basic"
+	authBearer = "bearer"
+	authQuery  = "query"
+)
+
+// Query parameter name endings that are masked whenever a URL is printed
+var sensitiveParams = []string{
+	"token", "key", "secret", "password", "passwd", "auth", "sig", "signature", "session",
+}
+
+type feedCredential struct {
+	Type   string
+	Name   string
+	Secret string
+}
+
+// Applies the credential to an outgoing feed request
+func (c feedCredential) apply(req *http.Request) {
+	switch c.Type {
+	case authBasic:
+		req.SetBasicAuth(c.Name, c.Secret)
+	case authBearer:
+		req.Header.Set("Authorization", "Bearer "+c.Secret)
+	case authQuery:
+		q := req.URL.Query()
+		q.Set(c.Name, c.Secret)
+		req.URL.RawQuery = q.Encode()
+	}
+}
+
+// Returns the AES-256 key used to encrypt feed secrets, 32 random bytes in base64.
+// GATOR_CREDENTIAL_KEY takes priority over "credential_key" in the config file
+func (s State) credentialKey() ([]byte, error) {
+	key := os.Getenv(credentialKeyEnv)
+	if key == "" {
+		key = s.point.Credential_key
+	}
+	if key == "" {
+		return nil, apperr.New(apperr.Validation, "no credential key configured: set %v or credential_key in %v", credentialKeyEnv, configFileName)
+	}
+
+	// a passphrase hashed into a key is as weak as the passphrase, so only a random key is taken
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil || len(raw) != credentialKeySize {
+		return nil, apperr.New(apperr.Validation,
+			"credential key must be %v random bytes in base64, generate one with: openssl rand -base64 %v",
+			credentialKeySize, credentialKeySize)
+	}
+	return raw, nil
+}
+
+func encryptSecret(key []byte, secret string) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, apperr.Wrap(err, "cipher error")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, apperr.Wrap(err, "cipher error")
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, apperr.Wrap(err, "unable to generate nonce")
+	}
+
+	return gcm.Seal(nonce, nonce, []byte(secret), nil), nil
+}
+
+func decryptSecret(key []byte, data []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", apperr.Wrap(err, "cipher error")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", apperr.Wrap(err, "cipher error")
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("stored secret is corrupt")
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", errors.New("unable to decrypt secret: wrong credential key?")
+	}
+
+	return string(plain), nil
+}
+
+// Looks up and decrypts the credential stored for a feed url,
+// returns nil when the feed has none
+func (s State) feedCredential(ctx context.Context, fedURL string) (*feedCredential, error) {
+	stored, err := s.store.GetFeedCredentialByUrl(ctx, fedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperr.WrapDB(err, "unable to load feed credential")
+	}
+
+	key, err := s.credentialKey()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := decryptSecret(key, stored.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &feedCredential{
+		Type:   stored.AuthType,
+		Name:   stored.AuthName,
+		Secret: secret,
+	}, nil
+}
+
+// Encrypts and stores a credential for the given feed
+func (s State) saveFeedCredential(ctx context.Context, feedID uuid.UUID, cred feedCredential) error {
+	key, err := s.credentialKey()
+	if err != nil {
+		return err
+	}
+	secret, err := encryptSecret(key, cred.Secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.dbq.SetFeedCredential(ctx, database.SetFeedCredentialParams{
+		FeedID:    feedID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		AuthType:  cred.Type,
+		AuthName:  cred.Name,
+		Secret:    secret,
+	})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to save feed credential")
+	}
+	return nil
+}
+
+// Removes user info or a secret looking query parameter from a feed url,
+// returning it as a basic or query credential so it's stored encrypted
+func splitURLCredential(raw string) (string, *feedCredential, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", nil, apperr.WrapKind(apperr.Validation, err, "invalid url")
+	}
+
+	var creds []feedCredential
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		creds = append(creds, feedCredential{Type: authBasic, Name: u.User.Username(), Secret: pass})
+		u.User = nil
+	}
+	if u.RawQuery != "" {
+		var kept []string
+		for _, p := range strings.Split(u.RawQuery, "&") {
+			name, value, _ := strings.Cut(p, "=")
+			if !isSensitiveParam(name) {
+				kept = append(kept, p)
+				continue
+			}
+			name, err = url.QueryUnescape(name)
+			if err == nil {
+				value, err = url.QueryUnescape(value)
+			}
+			if err != nil {
+				return "", nil, apperr.WrapKind(apperr.Validation, err, "invalid query in url")
+			}
+			creds = append(creds, feedCredential{Type: authQuery, Name: name, Secret: value})
+		}
+		u.RawQuery = strings.Join(kept, "&")
+	}
+
+	switch len(creds) {
+	case 0:
+		return raw, nil, nil
+	case 1:
+		return u.String(), &creds[0], nil
+	}
+	// a feed stores one credential, keeping only some of them would leave the rest in the url
+	return "", nil, apperr.New(apperr.Validation,
+		"url has more than one credential, add it without them and use feedauth for the one it needs")
+}
+
+// Masks passwords and secret looking query parameters so a url is safe to print
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "[unparsable url]"
+	}
+
+	if _, hasPass := u.User.Password(); hasPass {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, p := range params {
+			name, _, found := strings.Cut(p, "=")
+			if found && isSensitiveParam(name) {
+				params[i] = name + "=xxxxx"
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	return u.String()
+}
+
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, sp := range sensitiveParams {
+		if strings.HasSuffix(name, sp) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hides the request url inside client errors, it may carry a query credential
+func redactError(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		uerr.URL = RedactURL(uerr.URL)
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+)
+
+func TestCredentialKey(t *testing.T) {
+	for _, tc := range []struct {
+		key string
+		ok  bool
+	}{
+		{"BNXzQpoKuDNVdgg64X8Ub9Ls4oQUjGbKkgAgI9gaIBI=", true},
+		{" BNXzQpoKuDNVdgg64X8Ub9Ls4oQUjGbKkgAgI9gaIBI=\n", true},
+		{"some_long_random_string", false},
+		{"c2hvcnQ=", false},
+		{"", false},
+	} {
+		t.Setenv(credentialKeyEnv, "")
+		s := State{point: &Config{Credential_key: tc.key}}
+		key, err := s.credentialKey()
+		if tc.ok && (err != nil || len(key) != credentialKeySize) {
+			t.Errorf("credentialKey(%q) = %v, %v, want a %v byte key", tc.key, len(key), err, credentialKeySize)
+		}
+		if !tc.ok && apperr.KindOf(err) != apperr.Validation {
+			t.Errorf("credentialKey(%q) = %v, want a validation error", tc.key, err)
+		}
+	}
+
+	t.Setenv(credentialKeyEnv, "nZp0k2cmYEDMkrVVMyR0o3VQJ4f3WbsNjp0tJSXkqMM=")
+	s := State{point: &Config{Credential_key: "some_long_random_string"}}
+	key, err := s.credentialKey()
+	if err != nil {
+		t.Fatalf("credentialKey() = %v, want the environment key to win", err)
+	}
+	sealed, err := encryptSecret(key, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := decryptSecret(key, sealed)
+	if err != nil || plain != "hunter2" {
+		t.Errorf("decryptSecret() = %q, %v, want the secret back", plain, err)
+	}
+}
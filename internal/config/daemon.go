@@ -0,0 +1,475 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+)
+
+const (
+	defaultDaemonDir = ".gator"
+	pidFileName      = "gator.pid"
+	socketFileName   = "gator.sock"
+	daemonLogName    = "daemon.log"
+
+	// How often the daemon checks whether the config file changed
+	configCheckInterval = 5 * time.Second
+)
+
+var errDaemonNotRunning = apperr.New(apperr.NotFound, "the daemon isn't running, start it with gator daemon start <interval>")
+
+type daemonPaths struct {
+	dir    string
+	pid    string
+	socket string
+	log    string
+}
+
+// One request per connection to the control socket, answered by a controlResponse
+type controlRequest struct {
+	Command     string   `json:"command"`
+	Urls        []string `json:"urls,omitempty"`
+	Concurrency int      `json:"concurrency,omitempty"`
+}
+
+type controlResponse struct {
+	Error   string        `json:"error,omitempty"`
+	Kind    apperr.Kind   `json:"kind,omitempty"`
+	Status  *daemonStatus `json:"status,omitempty"`
+	Results []refreshRow  `json:"results,omitempty"`
+}
+
+type daemonStatus struct {
+	Pid      int            `json:"pid"`
+	Started  time.Time      `json:"started"`
+	Interval string         `json:"interval"`
+	LastPass time.Time      `json:"last_pass"`
+	InFlight []inFlightFeed `json:"in_flight"`
+	Queued   []string       `json:"queued"`
+	// Feeds not fetched within a full rotation, missing if the database didn't answer
+	Overdue *int64 `json:"overdue,omitempty"`
+}
+
+func HandlerDaemon(s *State, cmd Command, user database.User) error {
+	paths, err := s.daemonPaths()
+	if err != nil {
+		return err
+	}
+
+	switch cmd.Arguments[0] {
+	case "start":
+		return daemonStart(s, cmd, paths)
+	case "run":
+		return daemonRun(s, cmd, paths)
+	case "stop":
+		return daemonStop(s, paths)
+	case "status":
+		return daemonStatusPrint(s)
+	case "reload":
+		_, err := s.daemonRequest(controlRequest{Command: "reload"})
+		if err != nil {
+			return err
+		}
+		fmt.Println("Config reloaded")
+		return nil
+	}
+	return apperr.New(apperr.Validation, "unknown daemon action %v", cmd.Arguments[0])
+}
+
+func (s *State) daemonPaths() (daemonPaths, error) {
+	dir := s.point.Daemon_dir
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return daemonPaths{}, apperr.Wrap(err, "unable to find home dir")
+		}
+		dir = filepath.Join(home, defaultDaemonDir)
+	}
+	return daemonPaths{
+		dir:    dir,
+		pid:    filepath.Join(dir, pidFileName),
+		socket: filepath.Join(dir, socketFileName),
+		log:    filepath.Join(dir, daemonLogName),
+	}, nil
+}
+
+// Starts "gator daemon run" in the background and waits until it answers
+func daemonStart(s *State, cmd Command, paths daemonPaths) error {
+	if pid, running := daemonPID(paths); running {
+		return apperr.New(apperr.Conflict, "the daemon is already running (pid %v)", pid)
+	}
+	if len(cmd.Arguments) < 2 {
+		return apperr.New(apperr.Validation, "daemon start takes the interval between passes\nEx: gator daemon start 1m")
+	}
+	_, err := time.ParseDuration(cmd.Arguments[1])
+	if err != nil {
+		return apperr.WrapKind(apperr.Validation, err, "unable to parse duration")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return apperr.Wrap(err, "unable to find the gator executable")
+	}
+	err = os.MkdirAll(paths.dir, 0700)
+	if err != nil {
+		return apperr.Wrap(err, "unable to create %v", paths.dir)
+	}
+	logFile, err := os.OpenFile(paths.log, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return apperr.Wrap(err, "unable to open daemon log")
+	}
+	defer logFile.Close()
+
+	args := append([]string{"daemon", "run", cmd.Arguments[1]}, givenFlags(cmd.Flags)...)
+	proc := exec.Command(exe, args...)
+	proc.Stdout = logFile
+	proc.Stderr = logFile
+	detach(proc)
+	err = proc.Start()
+	if err != nil {
+		return apperr.Wrap(err, "unable to start the daemon")
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- proc.Wait()
+	}()
+
+	deadline := time.After(10 * time.Second)
+	for {
+		_, err := s.daemonRequest(controlRequest{Command: "status"})
+		if err == nil {
+			fmt.Printf("Daemon started (pid %v), logging to %v\n", proc.Process.Pid, paths.log)
+			return nil
+		}
+		select {
+		case err := <-exited:
+			return apperr.New(apperr.Internal, "the daemon exited right away (%v), see %v", err, paths.log)
+		case <-deadline:
+			return apperr.New(apperr.Internal, "the daemon didn't answer in time, see %v", paths.log)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Flags given on the command line, to hand on to the daemon process
+func givenFlags(fs *flag.FlagSet) []string {
+	var args []string
+	if fs == nil {
+		return args
+	}
+	fs.Visit(func(f *flag.Flag) {
+		args = append(args, fmt.Sprintf("--%v=%v", f.Name, f.Value.String()))
+	})
+	return args
+}
+
+// Runs the aggregator in the foreground with the control socket open,
+// until it's stopped through the socket or by a signal
+func daemonRun(s *State, cmd Command, paths daemonPaths) error {
+	aggCmd := cmd
+	aggCmd.Arguments = cmd.Arguments[1:]
+	agg, err := newAggregator(s, aggCmd)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(paths.dir, 0700)
+	if err != nil {
+		return apperr.Wrap(err, "unable to create %v", paths.dir)
+	}
+	err = writePIDFile(paths)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(paths.pid)
+
+	ln, err := listenControl(paths)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go serveControl(ctx, ln, agg, stop)
+	go watchConfig(ctx, agg)
+
+	s.log.Info("daemon started", "pid", os.Getpid(), "socket", paths.socket)
+	return agg.run(ctx)
+}
+
+// Claims the pid file, a file left behind by a daemon that died is replaced
+func writePIDFile(paths daemonPaths) error {
+	if pid, running := daemonPID(paths); running && pid != os.Getpid() {
+		return apperr.New(apperr.Conflict, "the daemon is already running (pid %v)", pid)
+	}
+	err := os.WriteFile(paths.pid, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+	if err != nil {
+		return apperr.Wrap(err, "unable to write pid file")
+	}
+	return nil
+}
+
+// Pid from the pid file and whether that process is still alive
+func daemonPID(paths daemonPaths) (int, bool) {
+	raw, err := os.ReadFile(paths.pid)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, processAlive(pid)
+}
+
+// Listens on the control socket, a socket nobody answers on is left over
+// from a daemon that died and is removed
+func listenControl(paths daemonPaths) (net.Listener, error) {
+	conn, err := net.DialTimeout("unix", paths.socket, time.Second)
+	if err == nil {
+		conn.Close()
+		return nil, apperr.New(apperr.Conflict, "another daemon is listening on %v", paths.socket)
+	}
+	err = os.Remove(paths.socket)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, apperr.Wrap(err, "unable to remove stale socket")
+	}
+
+	ln, err := net.Listen("unix", paths.socket)
+	if err != nil {
+		return nil, apperr.Wrap(err, "unable to listen on %v", paths.socket)
+	}
+	// only the user running the daemon gets to control it
+	err = os.Chmod(paths.socket, 0600)
+	if err != nil {
+		ln.Close()
+		return nil, apperr.Wrap(err, "unable to restrict %v", paths.socket)
+	}
+	return ln, nil
+}
+
+func serveControl(ctx context.Context, ln net.Listener, agg *aggregator, stop func()) {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go handleControl(ctx, conn, agg, stop)
+	}
+}
+
+func handleControl(ctx context.Context, conn net.Conn, agg *aggregator, stop func()) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	var req controlRequest
+	var resp controlResponse
+	err := json.NewDecoder(conn).Decode(&req)
+	if err == nil {
+		err = agg.control(ctx, req, &resp)
+	} else {
+		err = apperr.WrapKind(apperr.Validation, err, "invalid request")
+	}
+	if err != nil {
+		resp.Error = apperr.Message(err, false)
+		resp.Kind = apperr.KindOf(err)
+	} else if req.Command == "stop" {
+		// answer first, the process may be gone soon after
+		defer stop()
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+func (a *aggregator) control(ctx context.Context, req controlRequest, resp *controlResponse) error {
+	var err error
+	switch req.Command {
+	case "status":
+		resp.Status = a.status(ctx)
+	case "refresh":
+		if len(req.Urls) == 0 {
+			return apperr.New(apperr.Validation, "refresh needs at least 1 url")
+		}
+		resp.Results, err = a.requestRefresh(ctx, req.Urls, max(req.Concurrency, 1))
+	case "reload":
+		err = a.requestReload(ctx)
+	case "stop":
+	default:
+		err = apperr.New(apperr.Validation, "unknown control command %q", req.Command)
+	}
+	return err
+}
+
+func (a *aggregator) status(ctx context.Context) *daemonStatus {
+	a.mu.Lock()
+	st := &daemonStatus{
+		Pid:      os.Getpid(),
+		Started:  a.started,
+		Interval: a.interval.String(),
+		LastPass: a.lastPass,
+		InFlight: []inFlightFeed{},
+		Queued:   append([]string{}, a.queued...),
+	}
+	for _, f := range a.inFlight {
+		st.InFlight = append(st.InFlight, f)
+	}
+	a.mu.Unlock()
+
+	n, err := a.s.countOverdue(ctx, a.interval)
+	if err == nil {
+		st.Overdue = &n
+	}
+	return st
+}
+
+// Reloads whenever the config file changes, or on SIGHUP
+func watchConfig(ctx context.Context, agg *aggregator) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(configCheckInterval)
+	defer ticker.Stop()
+	last := configModTime()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-ticker.C:
+			mod := configModTime()
+			if mod.Equal(last) {
+				continue
+			}
+			last = mod
+		}
+		err := agg.requestReload(ctx)
+		if err != nil {
+			// the daemon carries on with the config it had
+			agg.s.log.Error("unable to reload config", "err", err)
+		}
+	}
+}
+
+// Modification time of the config file, zero when it can't be read
+func configModTime() time.Time {
+	path, err := configPath()
+	if err != nil {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func daemonStop(s *State, paths daemonPaths) error {
+	pid, running := daemonPID(paths)
+	_, err := s.daemonRequest(controlRequest{Command: "stop"})
+	if err != nil {
+		if !running {
+			return err
+		}
+		// the socket is gone but the process isn't
+		err = terminate(pid)
+		if err != nil {
+			return apperr.Wrap(err, "unable to stop the daemon (pid %v)", pid)
+		}
+	}
+	if pid == 0 {
+		fmt.Println("Daemon stopping")
+		return nil
+	}
+
+	// the daemon cancels whatever it's fetching, so this shouldn't take long
+	deadline := time.Now().Add(30 * time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			return apperr.New(apperr.Internal, "the daemon (pid %v) is still running after 30s", pid)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	fmt.Printf("Daemon stopped (pid %v)\n", pid)
+	return nil
+}
+
+func daemonStatusPrint(s *State) error {
+	resp, err := s.daemonRequest(controlRequest{Command: "status"})
+	if err != nil {
+		return err
+	}
+	st := resp.Status
+	if st == nil {
+		return apperr.New(apperr.Internal, "the daemon sent no status")
+	}
+
+	fmt.Printf("Daemon running (pid %v) since %v, a pass every %v\n",
+		st.Pid, st.Started.Format(time.DateTime), st.Interval)
+	if !st.LastPass.IsZero() {
+		fmt.Printf("Last pass: %v ago\n", time.Since(st.LastPass).Round(time.Second))
+	}
+	if st.Overdue != nil {
+		fmt.Printf("Overdue feeds: %v\n", *st.Overdue)
+	}
+	if len(st.InFlight) > 0 {
+		fmt.Println("In flight:")
+		for _, f := range st.InFlight {
+			fmt.Printf("  %v - %v (%v)\n", f.Name, f.Url, time.Since(f.Since).Round(time.Second))
+		}
+	}
+	if len(st.Queued) > 0 {
+		fmt.Println("Queued refreshes:")
+		for _, url := range st.Queued {
+			fmt.Printf("  %v\n", RedactURL(url))
+		}
+	}
+	return nil
+}
+
+// Sends req to the daemon and waits for its answer, errors the daemon
+// reports keep their kind
+func (s *State) daemonRequest(req controlRequest) (controlResponse, error) {
+	var resp controlResponse
+	paths, err := s.daemonPaths()
+	if err != nil {
+		return resp, err
+	}
+	conn, err := net.DialTimeout("unix", paths.socket, 2*time.Second)
+	if err != nil {
+		return resp, errDaemonNotRunning
+	}
+	defer conn.Close()
+
+	err = json.NewEncoder(conn).Encode(req)
+	if err != nil {
+		return resp, apperr.Wrap(err, "unable to send request to the daemon")
+	}
+	err = json.NewDecoder(conn).Decode(&resp)
+	if err != nil {
+		return resp, apperr.Wrap(err, "unable to read the daemon's answer")
+	}
+	if resp.Error != "" {
+		return resp, apperr.New(resp.Kind, "%v", resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,28 @@
+//go:build !unix
+
+package config
+
+import (
+	"os"
+	"os/exec"
+)
+
+func detach(proc *exec.Cmd) {}
+
+// Without signal 0 the best we can do is ask whether the pid exists
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	proc.Release()
+	return true
+}
+
+func terminate(pid int) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.Kill()
+}
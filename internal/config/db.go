@@ -0,0 +1,72 @@
+package config
+
+import (
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/ScooballyD/gator/internal/database/sqlite"
+	"github.com/ScooballyD/gator/internal/migrate"
+	"github.com/ScooballyD/gator/sql/schema"
+	sqliteschema "github.com/ScooballyD/gator/sql/sqlite/schema"
+)
+
+const sqliteScheme = "sqlite://"
+
+// A database gator can keep its data in
+type backend struct {
+	driver  string
+	dialect migrate.Dialect
+	schema  fs.FS
+	queries func(db database.DBTX) database.Querier
+}
+
+var postgresBackend = backend{
+	driver:  "postgres",
+	dialect: migrate.Postgres,
+	schema:  schema.FS,
+	queries: func(db database.DBTX) database.Querier { return database.New(db) },
+}
+
+var sqliteBackend = backend{
+	driver:  "sqlite3",
+	dialect: migrate.SQLite,
+	schema:  sqliteschema.FS,
+	queries: func(db database.DBTX) database.Querier { return sqlite.New(db) },
+}
+
+// Opens the database db_url points at, sqlite:///path/to/gator.db for
+// SQLite and anything else is a Postgres connection string
+func openDB(url string) (*sql.DB, backend, error) {
+	path, isSQLite := strings.CutPrefix(url, sqliteScheme)
+	if !isSQLite {
+		db, err := sql.Open(postgresBackend.driver, url)
+		if err != nil {
+			return nil, backend{}, apperr.WrapKind(apperr.DB, err, "failed to open database")
+		}
+		return db, postgresBackend, nil
+	}
+
+	if path == "" {
+		return nil, backend{}, apperr.New(apperr.Validation,
+			"db_url needs a path to the database file\nEx: 'sqlite:///home/you/.gator/gator.db'")
+	}
+	if path != ":memory:" {
+		err := os.MkdirAll(filepath.Dir(path), 0o700)
+		if err != nil {
+			return nil, backend{}, apperr.Wrap(err, "unable to create the database directory")
+		}
+	}
+	// cascading deletes need foreign keys, which SQLite leaves off by default
+	db, err := sql.Open(sqliteBackend.driver, path+"?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, backend{}, apperr.WrapKind(apperr.DB, err, "failed to open database")
+	}
+	// one writer at a time, the busy timeout covers other gator processes
+	db.SetMaxOpenConns(1)
+	return db, sqliteBackend, nil
+}
@@ -0,0 +1,394 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const defaultDownloadDir = "gator-downloads"
+
+var errSkipped = errors.New("skipped")
+
+type downloadOptions struct {
+	dir         string
+	maxBytes    int64
+	types       []string
+	concurrency int
+	// progress goes to the log instead of stdout, agg downloads in the background
+	logProgress bool
+}
+
+// A single enclosure queued for download
+type episode struct {
+	ID        uuid.UUID
+	Url       string
+	Type      string
+	Length    int64
+	PostTitle string
+	FeedName  string
+}
+
+// Registers the download filter flags shared by download and agg
+func downloadFlags(s *State, fs *flag.FlagSet) {
+	fs.String("max-size", "", "skip enclosures larger than this, ex: 200MB")
+	fs.String("type", "", "only download enclosures whose type starts with one of these, ex: audio/,video/mp4")
+	fs.Int("concurrency", 2, "number of downloads to run at once")
+}
+
+// Reads the flags registered by downloadFlags
+func downloadOptionsFrom(s *State, cmd Command) (downloadOptions, error) {
+	opts := downloadOptions{
+		dir:         s.point.Download_dir,
+		concurrency: cmd.intFlag("concurrency"),
+	}
+	if opts.dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return downloadOptions{}, apperr.Wrap(err, "unable to find home dir")
+		}
+		opts.dir = filepath.Join(home, defaultDownloadDir)
+	}
+	if opts.concurrency < 1 {
+		return downloadOptions{}, apperr.New(apperr.Validation, "concurrency must be at least 1")
+	}
+	if maxSize := cmd.stringFlag("max-size"); maxSize != "" {
+		n, err := parseBytes(maxSize)
+		if err != nil {
+			return downloadOptions{}, err
+		}
+		opts.maxBytes = n
+	}
+	for _, t := range strings.Split(cmd.stringFlag("type"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			opts.types = append(opts.types, strings.ToLower(t))
+		}
+	}
+	return opts, nil
+}
+
+// Parses sizes like 512, 20KB, 1.5GB, using the same 1024 units as formatBytes
+func parseBytes(raw string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(raw))
+	mult := float64(1)
+	for i, unit := range []string{"KB", "MB", "GB", "TB"} {
+		if strings.HasSuffix(str, unit) {
+			str = strings.TrimSuffix(str, unit)
+			mult = float64(int64(1) << (10 * (i + 1)))
+			break
+		}
+	}
+	str = strings.TrimSuffix(str, "B")
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil || n < 0 {
+		return 0, apperr.New(apperr.Validation, "invalid size %q: expected a value like 200MB", raw)
+	}
+	return int64(n * mult), nil
+}
+
+// Checks the size and type filters against what the feed claims
+func (opts downloadOptions) allows(ep episode) error {
+	if opts.maxBytes > 0 && ep.Length > opts.maxBytes {
+		return fmt.Errorf("%w: %v is over the %v limit", errSkipped, formatBytes(ep.Length), formatBytes(opts.maxBytes))
+	}
+	if len(opts.types) == 0 {
+		return nil
+	}
+	for _, t := range opts.types {
+		if strings.HasPrefix(strings.ToLower(ep.Type), t) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: type %q not wanted", errSkipped, ep.Type)
+}
+
+// Downloads episodes with at most opts.concurrency running at once,
+// recording each finished file against the user
+func (s State) downloadEpisodes(ctx context.Context, user database.User, eps []episode, opts downloadOptions) error {
+	err := os.MkdirAll(opts.dir, 0755)
+	if err != nil {
+		return apperr.Wrap(err, "unable to create download dir")
+	}
+
+	sem := make(chan struct{}, opts.concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+
+	for _, ep := range eps {
+		if err := opts.allows(ep); err != nil {
+			s.reportEpisode(opts, ep, "", 0, err)
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(ep episode) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			dest := episodePath(opts.dir, ep)
+			n, err := s.downloadFile(ctx, ep.Url, dest, opts.maxBytes)
+			if err == nil {
+				err = s.dbq.MarkEnclosureDownloaded(ctx, database.MarkEnclosureDownloadedParams{
+					UserID:       user.ID,
+					EnclosureID:  ep.ID,
+					DownloadedAt: time.Now(),
+					Path:         dest,
+					Bytes:        n,
+				})
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && !errors.Is(err, errSkipped) {
+				failed++
+			}
+			s.reportEpisode(opts, ep, dest, n, err)
+		}(ep)
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		return apperr.New(apperr.Network, "%v of %v downloads failed", failed, len(eps))
+	}
+	return nil
+}
+
+// Downloads the enclosures the user hasn't got yet from posts saved since the given time
+func (s State) downloadPending(ctx context.Context, user database.User, since time.Time, opts downloadOptions) error {
+	encs, err := s.dbq.GetPendingEnclosuresForUser(ctx, database.GetPendingEnclosuresForUserParams{
+		UserID:    user.ID,
+		CreatedAt: since,
+	})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to get pending episodes")
+	}
+
+	eps := make([]episode, 0, len(encs))
+	for _, enc := range encs {
+		ep := episode{
+			ID:        enc.ID,
+			Url:       enc.Url,
+			Type:      enc.Type,
+			Length:    enc.Length,
+			PostTitle: enc.PostTitle,
+			FeedName:  enc.FeedName,
+		}
+		// filtered episodes stay pending, don't report them again every pass
+		if opts.allows(ep) == nil {
+			eps = append(eps, ep)
+		}
+	}
+	opts.logProgress = true
+	return s.downloadEpisodes(ctx, user, eps, opts)
+}
+
+// Tells how an episode went, printed for download and logged for agg
+func (s State) reportEpisode(opts downloadOptions, ep episode, dest string, n int64, err error) {
+	if !opts.logProgress {
+		if err != nil {
+			fmt.Printf("%v - %v: %v\n", ep.FeedName, ep.PostTitle, err)
+			return
+		}
+		fmt.Printf("%v - %v: saved %v to %v\n", ep.FeedName, ep.PostTitle, formatBytes(n), dest)
+		return
+	}
+
+	log := s.log.With("feed", ep.FeedName, "title", ep.PostTitle, "enclosure_id", ep.ID)
+	switch {
+	case errors.Is(err, errSkipped):
+		log.Info("skipped episode", "reason", err)
+	case err != nil:
+		log.Error("download failed", "url", RedactURL(ep.Url), "err", err)
+	default:
+		log.Info("downloaded episode", "bytes", n, "path", dest)
+	}
+}
+
+// Builds <dir>/<feed>/<title> <id><ext> for an episode, the start of the
+// enclosure id keeps episodes with the same title apart
+func episodePath(dir string, ep episode) string {
+	ext := ""
+	if u, err := url.Parse(ep.Url); err == nil {
+		ext = path.Ext(u.Path)
+	}
+	if ext == "" {
+		if exts, _ := mime.ExtensionsByType(ep.Type); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+
+	name := ep.ID.String()[:8]
+	if title := safeFileName(ep.PostTitle); title != "" {
+		name = title + " " + name
+	}
+	return filepath.Join(dir, safeFileName(ep.FeedName), name+ext)
+}
+
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r == ':' || r == '*' || r == '?' || r == '"' || r == '<' || r == '>' || r == '|':
+			return '_'
+		case r < ' ':
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if len(name) > 150 {
+		name = strings.ToValidUTF8(name[:150], "")
+	}
+	return name
+}
+
+// Downloads url to dest through a .part file, resuming a previous partial
+// download when the server supports range requests, returns the file size
+func (s State) downloadFile(ctx context.Context, rawURL, dest string, maxBytes int64) (int64, error) {
+	err := os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return 0, apperr.Wrap(err, "unable to create dir")
+	}
+	if info, err := os.Stat(dest); err == nil {
+		return info.Size(), nil
+	}
+
+	part := dest + ".part"
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return 0, apperr.Wrap(err, "unable to send request")
+	}
+	req.Header.Add("User-Agent", "gator")
+	if offset > 0 {
+		req.Header.Add("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return 0, apperr.Wrap(redactError(err), "response error")
+	}
+	defer resp.Body.Close()
+
+	// a .part file the server's answer doesn't line up with is thrown away
+	restart := func() (int64, error) {
+		resp.Body.Close()
+		if offset == 0 {
+			return 0, apperr.New(apperr.Network, "unexpected Content-Range %q for the whole file", resp.Header.Get("Content-Range"))
+		}
+		err := os.Remove(part)
+		if err != nil {
+			return 0, apperr.Wrap(err, "unable to remove partial download")
+		}
+		return s.downloadFile(ctx, rawURL, dest, maxBytes)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, _, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return restart()
+		}
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// server ignored the range, start over
+		offset = 0
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// nothing past the end of the .part file, done if that's the whole file
+		_, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || total != offset {
+			return restart()
+		}
+		return offset, os.Rename(part, dest)
+	default:
+		return 0, apperr.New(apperr.Network, "unexpected response status: %v", resp.Status)
+	}
+
+	if maxBytes > 0 && resp.ContentLength > 0 && offset+resp.ContentLength > maxBytes {
+		return 0, fmt.Errorf("%w: %v is over the %v limit", errSkipped, formatBytes(offset+resp.ContentLength), formatBytes(maxBytes))
+	}
+
+	f, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return 0, apperr.Wrap(err, "unable to open file")
+	}
+
+	var body io.Reader = resp.Body
+	if maxBytes > 0 {
+		// one byte past the limit is enough to tell the file is too big
+		body = io.LimitReader(resp.Body, maxBytes-offset+1)
+	}
+	n, err := io.Copy(f, body)
+	closeErr := f.Close()
+	if err != nil {
+		// keep the .part file so the next attempt can resume
+		return 0, apperr.Wrap(err, "download interrupted after %v", formatBytes(offset+n))
+	}
+	if closeErr != nil {
+		return 0, apperr.Wrap(closeErr, "unable to write file")
+	}
+	if maxBytes > 0 && offset+n > maxBytes {
+		os.Remove(part)
+		return 0, fmt.Errorf("%w: larger than the %v limit", errSkipped, formatBytes(maxBytes))
+	}
+
+	err = os.Rename(part, dest)
+	if err != nil {
+		return 0, apperr.Wrap(err, "unable to move finished download")
+	}
+	return offset + n, nil
+}
+
+// Reads a Content-Range header, "bytes 100-199/1000" or "bytes */1000",
+// start is -1 for the second form and total -1 when the size is unknown
+func parseContentRange(header string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(header), "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, false
+		}
+		total = n
+	}
+	if rng == "*" {
+		return -1, total, true
+	}
+	first, _, found := strings.Cut(rng, "-")
+	start, err := strconv.ParseInt(first, 10, 64)
+	if !found || err != nil || start < 0 {
+		return 0, 0, false
+	}
+	return start, total, true
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestEpisodePath(t *testing.T) {
+	first := episode{
+		ID:        uuid.MustParse("3f2a9c1d-0000-4000-8000-000000000001"),
+		Url:       "https://example.com/ep/1.mp3?token=x",
+		PostTitle: "Episode: 1",
+		FeedName:  "Show",
+	}
+	got := episodePath("/dl", first)
+	want := filepath.Join("/dl", "Show", "Episode_ 1 3f2a9c1d.mp3")
+	if got != want {
+		t.Errorf("episodePath() = %q, want %q", got, want)
+	}
+
+	// titles that clean up to the same name still get files of their own
+	second := first
+	second.ID = uuid.MustParse("7b01e4aa-0000-4000-8000-000000000002")
+	second.PostTitle = "Episode* 1"
+	if episodePath("/dl", second) == got {
+		t.Errorf("two episodes share the path %q", got)
+	}
+
+	untitled := episode{ID: first.ID, Type: "audio/mpeg", FeedName: "Show"}
+	got = episodePath("/dl", untitled)
+	if filepath.Base(got)[:8] != "3f2a9c1d" || filepath.Ext(got) == "" {
+		t.Errorf("episodePath() = %q for an untitled episode, want its id and an extension from the type", got)
+	}
+}
+
+func TestDownloadFileResume(t *testing.T) {
+	const body = "0123456789"
+	// mode picks how the server answers a range request, a request without one gets the whole file
+	var mode string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rng := r.Header.Get("Range")
+		switch {
+		case rng == "":
+			w.Write([]byte(body))
+		case mode == "resume":
+			var start int
+			fmt.Sscanf(rng, "bytes=%d-", &start)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(body)-1, len(body)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(body[start:]))
+		case mode == "wrong range":
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(body)-1, len(body)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(body))
+		case mode == "done":
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", len(body)))
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	s, _ := newTestState(t, srv)
+
+	for _, tc := range []struct {
+		mode string
+		part string
+	}{
+		{mode: "resume", part: "0123"},
+		{mode: "wrong range", part: "0123"},
+		{mode: "done", part: body},
+		// a .part file that's neither complete nor a prefix is thrown away
+		{mode: "done", part: "012"},
+	} {
+		mode = tc.mode
+		dest := filepath.Join(t.TempDir(), "episode.mp3")
+		err := os.WriteFile(dest+".part", []byte(tc.part), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := s.downloadFile(context.Background(), srv.URL, dest, 0)
+		if err != nil {
+			t.Errorf("%v: downloadFile() = %v", tc.mode, err)
+			continue
+		}
+		got, _ := os.ReadFile(dest)
+		if string(got) != body || n != int64(len(body)) {
+			t.Errorf("%v from %q: downloaded %q (%v bytes), want %q", tc.mode, tc.part, got, n, body)
+		}
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	for _, tc := range []struct {
+		header       string
+		start, total int64
+		ok           bool
+	}{
+		{"bytes 100-199/1000", 100, 1000, true},
+		{"bytes 100-199/*", 100, -1, true},
+		{"bytes */1000", -1, 1000, true},
+		{"items 0-1/2", 0, 0, false},
+		{"bytes 100-199", 0, 0, false},
+		{"", 0, 0, false},
+	} {
+		start, total, ok := parseContentRange(tc.header)
+		if start != tc.start || total != tc.total || ok != tc.ok {
+			t.Errorf("parseContentRange(%q) = %v, %v, %v, want %v, %v, %v", tc.header, start, total, ok, tc.start, tc.total, tc.ok)
+		}
+	}
+}
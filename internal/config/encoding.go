@@ -0,0 +1,89 @@
+package config
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encodings offered to servers, in order of preference
+const acceptEncoding = "zstd, br, gzip, deflate"
+
+// Byte counts for a single feed request, WireBytes is the response body as sent
+// and DecodedBytes is what it expanded to after content decoding
+type FetchStats struct {
+	StatusCode   int
+	Encoding     string
+	WireBytes    int64
+	DecodedBytes int64
+	// The body arrived but isn't a feed gator can read
+	ParseFailed bool
+}
+
+type countingReader struct {
+	r     io.Reader
+	count *int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.count += int64(n)
+	return n, err
+}
+
+// Wraps a response body with the decoder for its Content-Encoding header
+func decodeBody(encoding string, body io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.NopCloser(body), nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, apperr.Wrap(err, "invalid gzip body")
+		}
+		return zr, nil
+	case "deflate":
+		zr, err := zlib.NewReader(body)
+		if err != nil {
+			return nil, apperr.Wrap(err, "invalid deflate body")
+		}
+		return zr, nil
+	case "br":
+		return io.NopCloser(brotli.NewReader(body)), nil
+	case "zstd":
+		zr, err := zstd.NewReader(body)
+		if err != nil {
+			return nil, apperr.Wrap(err, "invalid zstd body")
+		}
+		return zr.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
+
+// Formats a byte count for display, ex: 1.5MB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Share of the decoded size that compression kept off the wire
+func savedPercent(wire, decoded int64) string {
+	if decoded == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", 100-float64(wire)*100/float64(decoded))
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	actionHide     = "hide"
+	actionStar     = "star"
+	actionMarkRead = "markread"
+)
+
+// A stored filter rule with its patterns compiled
+type filterRule struct {
+	database.FilterRule
+	title  *regexp.Regexp
+	author *regexp.Regexp
+}
+
+func compileRule(rule database.FilterRule) (filterRule, error) {
+	r := filterRule{FilterRule: rule}
+	var err error
+	if rule.TitlePattern != "" {
+		r.title, err = regexp.Compile(rule.TitlePattern)
+		if err != nil {
+			return r, apperr.WrapKind(apperr.Validation, err, "invalid title regex")
+		}
+	}
+	if rule.AuthorPattern != "" {
+		r.author, err = regexp.Compile(rule.AuthorPattern)
+		if err != nil {
+			return r, apperr.WrapKind(apperr.Validation, err, "invalid author regex")
+		}
+	}
+	return r, nil
+}
+
+// Compiles stored rules, a rule that no longer compiles is logged and skipped
+func compileRules(log *slog.Logger, rules []database.FilterRule) []filterRule {
+	out := make([]filterRule, 0, len(rules))
+	for _, rule := range rules {
+		r, err := compileRule(rule)
+		if err != nil {
+			log.Warn("skipping filter", "filter_id", rule.ID, "err", err)
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Every pattern set on the rule has to match for the rule to apply
+func (r filterRule) matches(pst database.Post) bool {
+	if r.FeedID.Valid && r.FeedID.UUID != pst.FeedID {
+		return false
+	}
+	if r.title != nil && !r.title.MatchString(pst.Title) {
+		return false
+	}
+	if r.author != nil && !r.author.MatchString(pst.Author) {
+		return false
+	}
+	if r.Keyword != "" {
+		kw := strings.ToLower(r.Keyword)
+		if !strings.Contains(strings.ToLower(pst.Title), kw) && !strings.Contains(strings.ToLower(pst.Description), kw) {
+			return false
+		}
+	}
+	return true
+}
+
+// Describes the rule's conditions for listing
+func (r filterRule) describe() string {
+	var parts []string
+	if r.TitlePattern != "" {
+		parts = append(parts, fmt.Sprintf("title =~ %q", r.TitlePattern))
+	}
+	if r.AuthorPattern != "" {
+		parts = append(parts, fmt.Sprintf("author =~ %q", r.AuthorPattern))
+	}
+	if r.Keyword != "" {
+		parts = append(parts, fmt.Sprintf("keyword %q", r.Keyword))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// Stores the result of a matching rule against the rule's owner
+func (s State) applyFilter(ctx context.Context, r filterRule, postID uuid.UUID) error {
+	var err error
+	switch r.Action {
+	case actionHide:
+		err = s.store.SetPostHidden(ctx, database.SetPostHiddenParams{
+			UserID: r.UserID,
+			PostID: postID,
+			Hidden: true,
+		})
+	case actionStar:
+		err = s.store.SetPostStarred(ctx, database.SetPostStarredParams{
+			UserID:  r.UserID,
+			PostID:  postID,
+			Starred: true,
+		})
+	case actionMarkRead:
+		err = s.store.MarkPostRead(ctx, database.MarkPostReadParams{
+			UserID: r.UserID,
+			PostID: postID,
+			ReadAt: sql.NullTime{Time: s.clock.Now(), Valid: true},
+		})
+	default:
+		return apperr.New(apperr.Validation, "unknown filter action %q", r.Action)
+	}
+	if err != nil {
+		return apperr.WrapDB(err, "unable to apply filter")
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+)
+
+// Creates a flag set that reports errors instead of printing usage and exiting
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// Parses flags found anywhere in args, the flag package alone stops at the
+// first positional argument, returns the positional arguments in order.
+// Everything after -- is positional
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		args = rest
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// Asks a yes or no question on the terminal, anything but y or yes is a no
+func confirm(question string) (bool, error) {
+	fmt.Printf("%v [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, apperr.Wrap(err, "unable to read answer")
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// Flags every command accepts, before the command name or among its own flags
+type GlobalFlags struct {
+	Output string
+	// Show the full cause and kind of errors
+	Debug bool
+}
+
+// Pulls the global flags given before the command name out of args, returns
+// what's left: the command name followed by its own arguments. After the
+// name they are parsed along with the command's flags by Run, so a value
+// that only looks like one is left alone
+func ParseGlobalFlags(args []string) (GlobalFlags, []string, error) {
+	var flags GlobalFlags
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || (name != "output" && name != "debug") {
+			return flags, args[i:], nil
+		}
+		if name == "debug" {
+			debug, err := strconv.ParseBool(value)
+			if !hasValue {
+				debug, err = true, nil
+			}
+			if err != nil {
+				return flags, nil, apperr.WrapKind(apperr.Validation, err, "invalid value for --debug")
+			}
+			flags.Debug = debug
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return flags, nil, apperr.New(apperr.Validation, "flag --%v needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		flags.Output = value
+	}
+	return flags, nil, nil
+}
+
+// Value of a flag parsed by Run, the zero value when the command has no such flag
+func (cmd Command) flagValue(name string) any {
+	if cmd.Flags == nil {
+		return nil
+	}
+	f := cmd.Flags.Lookup(name)
+	if f == nil {
+		return nil
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return nil
+	}
+	return getter.Get()
+}
+
+func (cmd Command) boolFlag(name string) bool {
+	v, _ := cmd.flagValue(name).(bool)
+	return v
+}
+
+func (cmd Command) intFlag(name string) int {
+	v, _ := cmd.flagValue(name).(int)
+	return v
+}
+
+func (cmd Command) stringFlag(name string) string {
+	v, _ := cmd.flagValue(name).(string)
+	return v
+}
+
+// Reports whether a flag was given on the command line
+func (cmd Command) hasFlag(name string) bool {
+	found := false
+	if cmd.Flags != nil {
+		cmd.Flags.Visit(func(f *flag.Flag) {
+			if f.Name == name {
+				found = true
+			}
+		})
+	}
+	return found
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	tests := []struct {
+		args   []string
+		want   GlobalFlags
+		rest   []string
+		hasErr bool
+	}{
+		{args: []string{"--output", "json", "feeds"}, want: GlobalFlags{Output: "json"}, rest: []string{"feeds"}},
+		{args: []string{"--debug", "--output=csv", "browse", "5"}, want: GlobalFlags{Output: "csv", Debug: true}, rest: []string{"browse", "5"}},
+		// after the command name they belong to the command
+		{args: []string{"feeds", "--output", "json"}, rest: []string{"feeds", "--output", "json"}},
+		{args: []string{"addfeed", "--debug", "--", "--output"}, rest: []string{"addfeed", "--debug", "--", "--output"}},
+		{args: []string{"--output"}, hasErr: true},
+		{args: []string{"--debug=maybe", "feeds"}, hasErr: true},
+		{args: nil},
+	}
+	for _, tc := range tests {
+		got, rest, err := ParseGlobalFlags(tc.args)
+		if (err != nil) != tc.hasErr {
+			t.Errorf("ParseGlobalFlags(%q) error = %v, want error %v", tc.args, err, tc.hasErr)
+			continue
+		}
+		if got != tc.want || !slices.Equal(rest, tc.rest) {
+			t.Errorf("ParseGlobalFlags(%q) = %+v, %q, want %+v, %q", tc.args, got, rest, tc.want, tc.rest)
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	fs := newFlagSet("test")
+	output := fs.String("output", "", "")
+	args, err := parseFlags(fs, []string{"name", "--output", "json", "--", "--output", "url"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *output != "json" || !slices.Equal(args, []string{"name", "--output", "url"}) {
+		t.Errorf("parseFlags() = %q with --output %q, want the values after -- kept as arguments", args, *output)
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	s := &State{output: formatText}
+	err := s.SetOutput("xml")
+	if apperr.KindOf(err) != apperr.Validation || s.output != formatText {
+		t.Errorf("SetOutput(xml) = %v (%v), want a validation error and the format unchanged", err, apperr.KindOf(err))
+	}
+	err = s.SetOutput("json")
+	if err != nil || s.output != "json" {
+		t.Errorf("SetOutput(json) = %v, output %q", err, s.output)
+	}
+}
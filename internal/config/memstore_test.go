@@ -0,0 +1,277 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// feedStore kept in memory, behaving like the queries do against Postgres
+type memStore struct {
+	mu    sync.Mutex
+	clock clock
+
+	feeds       []database.Feed
+	fetches     []database.CreateFeedFetchParams
+	credentials map[string]database.FeedCredential
+	rules       []database.FilterRule
+	posts       []database.Post
+	categories  map[uuid.UUID][]string
+	enclosures  []database.CreatePostEnclosureParams
+	hidden      map[uuid.UUID]bool
+	starred     map[uuid.UUID]bool
+	read        map[uuid.UUID]bool
+
+	// Returned by every call when set, like a database that went away
+	err error
+}
+
+func newMemStore(c clock) *memStore {
+	return &memStore{
+		clock:       c,
+		credentials: map[string]database.FeedCredential{},
+		categories:  map[uuid.UUID][]string{},
+		hidden:      map[uuid.UUID]bool{},
+		starred:     map[uuid.UUID]bool{},
+		read:        map[uuid.UUID]bool{},
+	}
+}
+
+func (m *memStore) addFeed(name, url string) database.Feed {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	feed := database.Feed{
+		ID:        uuid.New(),
+		CreatedAt: m.clock.Now(),
+		UpdatedAt: m.clock.Now(),
+		Name:      name,
+		Url:       url,
+	}
+	m.feeds = append(m.feeds, feed)
+	return feed
+}
+
+func (m *memStore) postsFor(feedID uuid.UUID) []database.Post {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var posts []database.Post
+	for _, p := range m.posts {
+		if p.FeedID == feedID {
+			posts = append(posts, p)
+		}
+	}
+	return posts
+}
+
+// Same order as the query: never fetched first, then the longest since its last fetch
+func (m *memStore) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return database.Feed{}, m.err
+	}
+	if len(m.feeds) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	feeds := append([]database.Feed(nil), m.feeds...)
+	sort.SliceStable(feeds, func(i, j int) bool {
+		a, b := feeds[i].LastFetchedAt, feeds[j].LastFetchedAt
+		if a.Valid != b.Valid {
+			return !a.Valid
+		}
+		return a.Time.Before(b.Time)
+	})
+	return feeds[0], nil
+}
+
+func (m *memStore) GetFeed(ctx context.Context, url string) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return database.Feed{}, m.err
+	}
+	for _, f := range m.feeds {
+		if f.Url == url {
+			return f, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (m *memStore) MarkFeedFetched(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return database.Feed{}, m.err
+	}
+	for i, f := range m.feeds {
+		if f.ID == id {
+			m.feeds[i].LastFetchedAt = sql.NullTime{Time: m.clock.Now(), Valid: true}
+			m.feeds[i].UpdatedAt = m.clock.Now()
+			return m.feeds[i], nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (m *memStore) CountFeeds(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return 0, m.err
+	}
+	return int64(len(m.feeds)), nil
+}
+
+func (m *memStore) CountOverdueFeeds(ctx context.Context, cutoff sql.NullTime) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return 0, m.err
+	}
+	var n int64
+	for _, f := range m.feeds {
+		if !f.LastFetchedAt.Valid || f.LastFetchedAt.Time.Before(cutoff.Time) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memStore) CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.fetches = append(m.fetches, arg)
+	return nil
+}
+
+func (m *memStore) GetFeedCredentialByUrl(ctx context.Context, url string) (database.FeedCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return database.FeedCredential{}, m.err
+	}
+	cred, ok := m.credentials[url]
+	if !ok {
+		return database.FeedCredential{}, sql.ErrNoRows
+	}
+	return cred, nil
+}
+
+func (m *memStore) GetFilterRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.FilterRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	var rules []database.FilterRule
+	for _, r := range m.rules {
+		if !r.FeedID.Valid || r.FeedID.UUID == feedID {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// Like ON CONFLICT (feed_id, identity_key) DO NOTHING RETURNING *
+func (m *memStore) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return database.Post{}, m.err
+	}
+	for _, p := range m.posts {
+		if p.FeedID == arg.FeedID && p.IdentityKey == arg.IdentityKey {
+			return database.Post{}, sql.ErrNoRows
+		}
+	}
+	post := database.Post{
+		ID:              arg.ID,
+		CreatedAt:       arg.CreatedAt,
+		UpdatedAt:       arg.UpdatedAt,
+		Title:           arg.Title,
+		Url:             arg.Url,
+		Description:     arg.Description,
+		PublishedAt:     arg.PublishedAt,
+		FeedID:          arg.FeedID,
+		Guid:            arg.Guid,
+		GuidIsPermalink: arg.GuidIsPermalink,
+		Author:          arg.Author,
+		ContentEncoded:  arg.ContentEncoded,
+		Comments:        arg.Comments,
+		IdentityKey:     arg.IdentityKey,
+	}
+	m.posts = append(m.posts, post)
+	return post, nil
+}
+
+func (m *memStore) CreatePostCategory(ctx context.Context, arg database.CreatePostCategoryParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.categories[arg.PostID] = append(m.categories[arg.PostID], arg.Name)
+	return nil
+}
+
+func (m *memStore) CreatePostEnclosure(ctx context.Context, arg database.CreatePostEnclosureParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.enclosures = append(m.enclosures, arg)
+	return nil
+}
+
+func (m *memStore) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	for i, p := range m.posts {
+		if p.ID == arg.ID {
+			m.posts[i].Content = arg.Content
+		}
+	}
+	return nil
+}
+
+func (m *memStore) SetPostHidden(ctx context.Context, arg database.SetPostHiddenParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.hidden[arg.PostID] = arg.Hidden
+	return nil
+}
+
+func (m *memStore) SetPostStarred(ctx context.Context, arg database.SetPostStarredParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.starred[arg.PostID] = arg.Starred
+	return nil
+}
+
+func (m *memStore) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.read[arg.PostID] = arg.ReadAt.Valid
+	return nil
+}
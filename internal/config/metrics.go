@@ -0,0 +1,163 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/ScooballyD/gator/internal/metrics"
+)
+
+// What agg exposes on --metrics-addr, recorded whether or not it's served
+type aggMetrics struct {
+	registry      *metrics.Registry
+	fetches       *metrics.Counter
+	fetchSeconds  *metrics.Histogram
+	fetchBytes    *metrics.Histogram
+	bytes         *metrics.Counter
+	postsInserted *metrics.Counter
+	parseFailures *metrics.Counter
+	overdue       *metrics.Gauge
+	querySeconds  *metrics.Histogram
+}
+
+func newAggMetrics() *aggMetrics {
+	r := metrics.NewRegistry()
+	return &aggMetrics{
+		registry: r,
+		fetches: r.NewCounter("gator_feed_fetches_total",
+			"Feed fetches by response status, error when no response arrived", "status"),
+		fetchSeconds: r.NewHistogram("gator_feed_fetch_duration_seconds",
+			"Time taken to fetch, parse and store a feed", metrics.DefaultBuckets),
+		fetchBytes: r.NewHistogram("gator_feed_response_bytes",
+			"Size of feed responses as sent over the wire", metrics.ByteBuckets),
+		bytes: r.NewCounter("gator_feed_bytes_total",
+			"Bytes of feed responses, as sent and after content decoding", "stage"),
+		postsInserted: r.NewCounter("gator_posts_inserted_total",
+			"Posts stored for the first time"),
+		parseFailures: r.NewCounter("gator_feed_parse_failures_total",
+			"Feed responses or items that couldn't be parsed"),
+		overdue: r.NewGauge("gator_feeds_overdue",
+			"Feeds not fetched within a full rotation, the agg interval times the number of feeds"),
+		querySeconds: r.NewHistogram("gator_db_query_duration_seconds",
+			"Time taken by database queries", metrics.DefaultBuckets, "query"),
+	}
+}
+
+func (m *aggMetrics) observeFetch(stats FetchStats, duration time.Duration) {
+	status := "error"
+	if stats.StatusCode != 0 {
+		status = strconv.Itoa(stats.StatusCode)
+	}
+	m.fetches.Inc(status)
+	m.fetchSeconds.Observe(duration.Seconds())
+	if stats.StatusCode != 0 {
+		m.fetchBytes.Observe(float64(stats.WireBytes))
+		m.bytes.Add(float64(stats.WireBytes), "wire")
+		m.bytes.Add(float64(stats.DecodedBytes), "decoded")
+	}
+	if stats.ParseFailed {
+		m.parseFailures.Inc()
+	}
+}
+
+// Counts the feeds that fell behind, ex: passes that take longer than the
+// interval or a feed that keeps failing. Each pass fetches one feed, so a feed
+// is due again after the interval times the number of feeds, one interval
+// more is allowed before it counts
+func (s *State) countOverdue(ctx context.Context, interval time.Duration) (int64, error) {
+	feeds, err := s.store.CountFeeds(ctx)
+	if err != nil {
+		return 0, apperr.WrapDB(err, "unable to count feeds")
+	}
+	cutoff := s.clock.Now().Add(-interval * time.Duration(feeds+1))
+	n, err := s.store.CountOverdueFeeds(ctx, sql.NullTime{Time: cutoff, Valid: true})
+	if err != nil {
+		return 0, apperr.WrapDB(err, "unable to count overdue feeds")
+	}
+	return n, nil
+}
+
+func (s *State) updateOverdue(ctx context.Context, interval time.Duration) error {
+	n, err := s.countOverdue(ctx, interval)
+	if err != nil {
+		return err
+	}
+	s.metrics.overdue.Set(float64(n))
+	return nil
+}
+
+// Serves the metrics on addr until gator exits, the address is checked
+// before returning so a port in use fails agg right away
+func (s *State) serveMetrics(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return apperr.WrapKind(apperr.Validation, err, "unable to listen on metrics address %v", addr)
+	}
+	srv := &http.Server{
+		Handler:           s.metricsHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		err := srv.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error("metrics server stopped", "err", err)
+		}
+	}()
+	s.log.Info("serving metrics", "addr", ln.Addr().String(), "path", "/metrics")
+	return nil
+}
+
+func (s *State) metricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.metrics.registry)
+	return mux
+}
+
+// Times every query, labelled with the name sqlc gives it
+type timedDB struct {
+	db      database.DBTX
+	observe func(query string, d time.Duration)
+}
+
+func (t timedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer t.time(query, time.Now())
+	return t.db.ExecContext(ctx, query, args...)
+}
+
+func (t timedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	defer t.time(query, time.Now())
+	return t.db.PrepareContext(ctx, query)
+}
+
+// Only the time until the first rows arrive, reading them is up to the caller
+func (t timedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer t.time(query, time.Now())
+	return t.db.QueryContext(ctx, query, args...)
+}
+
+func (t timedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer t.time(query, time.Now())
+	return t.db.QueryRowContext(ctx, query, args...)
+}
+
+func (t timedDB) time(query string, started time.Time) {
+	t.observe(queryName(query), time.Since(started))
+}
+
+// Name from the "-- name: GetFeeds :many" line sqlc starts each query with
+func queryName(query string) string {
+	rest, found := strings.CutPrefix(query, "-- name: ")
+	if !found {
+		return "other"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
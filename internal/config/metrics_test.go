@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fetches a fixture and scrapes /metrics the way Prometheus would
+func TestMetricsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	srv := feedServer(t)
+	s, store := newTestState(t, srv)
+	store.addFeed("rss2", srv.URL+"/feeds/rss2.xml")
+	store.addFeed("broken", srv.URL+"/status/500")
+
+	for range 2 {
+		feed, found, err := nextFeed(context.Background(), s)
+		if err != nil || !found {
+			t.Fatalf("nextFeed() = %v, %v", found, err)
+		}
+		scrapeFeed(context.Background(), s, feed)
+	}
+	err := s.updateOverdue(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metricsSrv := httptest.NewServer(s.metricsHandler())
+	defer metricsSrv.Close()
+	scrape := func() string {
+		t.Helper()
+		resp, err := http.Get(metricsSrv.URL + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+			t.Errorf("Content-Type = %q, want the Prometheus text format", ct)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	body := scrape()
+	for _, want := range []string{
+		"# TYPE gator_feed_fetches_total counter\n",
+		`gator_feed_fetches_total{status="200"} 1` + "\n",
+		`gator_feed_fetches_total{status="500"} 1` + "\n",
+		"gator_posts_inserted_total 3\n",
+		"# TYPE gator_feed_fetch_duration_seconds histogram\n",
+		"gator_feed_fetch_duration_seconds_count 2\n",
+		`gator_feed_fetch_duration_seconds_bucket{le="+Inf"} 2` + "\n",
+		// both feeds were fetched within the rotation
+		"gator_feeds_overdue 0\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics is missing %q\n%v", want, body)
+		}
+	}
+
+	// two feeds at one a minute are each due every two minutes, with a minute to spare
+	s.clock.(*testClock).now = s.clock.Now().Add(2 * time.Minute)
+	err = s.updateOverdue(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := scrape(); !strings.Contains(body, "gator_feeds_overdue 0\n") {
+		t.Errorf("feeds are overdue within their rotation\n%v", body)
+	}
+	s.clock.(*testClock).now = s.clock.Now().Add(2 * time.Minute)
+	err = s.updateOverdue(ctx, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := scrape(); !strings.Contains(body, "gator_feeds_overdue 2\n") {
+		t.Errorf("feeds aren't overdue after missing their rotation\n%v", body)
+	}
+}
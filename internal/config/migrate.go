@@ -0,0 +1,170 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/migrate"
+)
+
+func newMigrator(s *State) (*migrate.Migrator, error) {
+	m, err := migrate.New(s.db, s.backend.dialect, s.backend.schema)
+	if err != nil {
+		return nil, apperr.Wrap(err, "unable to read migrations")
+	}
+	return m, nil
+}
+
+// Refuses to go on when the database isn't at the version this gator was
+// built for, queries against an older schema fail in confusing ways
+func checkSchema(ctx context.Context, s *State) error {
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+	current, err := m.Current(ctx)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to read the database version")
+	}
+	latest := m.Latest()
+	if current < latest {
+		return apperr.New(apperr.DB,
+			"the database is at version %v, gator needs version %v\nrun 'gator migrate up' to update it", current, latest)
+	}
+	if current > latest {
+		return apperr.New(apperr.DB,
+			"the database is at version %v, newer than this gator knows about (%v)\nupdate gator or run 'gator migrate to %v'", current, latest, latest)
+	}
+	return nil
+}
+
+func HandlerMigrate(s *State, cmd Command) error {
+	ctx := context.Background()
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+
+	action := cmd.Arguments[0]
+	if action != "to" && len(cmd.Arguments) > 1 {
+		return apperr.New(apperr.Validation, "migrate %v takes no version", action)
+	}
+
+	switch action {
+	case "status":
+		return migrateStatus(ctx, s, m)
+	case "up":
+		applied, err := m.Up(ctx)
+		printMigrations("Applied", applied)
+		if err != nil {
+			return apperr.WrapDB(err, "migration failed")
+		}
+	case "down":
+		current, err := m.Current(ctx)
+		if err != nil {
+			return apperr.WrapDB(err, "unable to read the database version")
+		}
+		err = checkSQLiteTarget(s, m, current-1)
+		if err != nil {
+			return err
+		}
+		mig, err := m.Down(ctx)
+		if err != nil {
+			return apperr.WrapDB(err, "rollback failed")
+		}
+		fmt.Printf("Rolled back %v\n", mig.File)
+	case "to":
+		if len(cmd.Arguments) < 2 {
+			return apperr.New(apperr.Validation, "migrate to takes a version\nEx: 'gator migrate to 12'")
+		}
+		version, err := strconv.ParseInt(cmd.Arguments[1], 10, 64)
+		if err != nil || version < 0 {
+			return apperr.New(apperr.Validation, "version must be a number, 0 rolls back everything")
+		}
+		err = checkSQLiteTarget(s, m, version)
+		if err != nil {
+			return err
+		}
+		current, err := m.Current(ctx)
+		if err != nil {
+			return apperr.WrapDB(err, "unable to read the database version")
+		}
+		done, err := m.To(ctx, version)
+		verb := "Applied"
+		if version < current {
+			verb = "Rolled back"
+		}
+		printMigrations(verb, done)
+		if err != nil {
+			return apperr.WrapDB(err, "migration failed")
+		}
+	}
+
+	current, err := m.Current(ctx)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to read the database version")
+	}
+	if current == m.Latest() {
+		n, err := normalizeLinkKeys(ctx, s)
+		if err != nil {
+			return apperr.WrapDB(err, "unable to normalize post links")
+		}
+		if n > 0 {
+			fmt.Printf("Normalized the links of %v posts\n", n)
+		}
+		n, err = fillLinkKeys(ctx, s)
+		if err != nil {
+			return apperr.WrapDB(err, "unable to store post links")
+		}
+		if n > 0 {
+			fmt.Printf("Stored the links of %v posts\n", n)
+		}
+	}
+	fmt.Printf("Database is at version %v\n", current)
+	return nil
+}
+
+// SQLite's schema starts out squashed into one migration, so there's no going
+// back to a version before it, only to 0 which drops every table
+func checkSQLiteTarget(s *State, m *migrate.Migrator, version int64) error {
+	if s.backend.driver != sqliteBackend.driver || version <= 0 || version >= m.First() {
+		return nil
+	}
+	return apperr.New(apperr.Validation,
+		"SQLite databases start at version %v, there are no older versions to go back to\n"+
+			"run 'gator migrate to 0' to drop every table", m.First())
+}
+
+func printMigrations(verb string, migrations []migrate.Migration) {
+	for _, mig := range migrations {
+		fmt.Printf("%v %v\n", verb, mig.File)
+	}
+}
+
+func migrateStatus(ctx context.Context, s *State, m *migrate.Migrator) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to read migrations from the database")
+	}
+	rows := make([]migrationRow, 0, len(statuses))
+	for _, st := range statuses {
+		rows = append(rows, migrationRow{
+			Version:   st.Version,
+			Name:      st.Name,
+			Applied:   st.Applied,
+			AppliedAt: st.AppliedAt,
+		})
+	}
+	return s.render(rows, func() error {
+		for _, r := range rows {
+			applied := "pending"
+			if r.Applied {
+				applied = r.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d %-24v %v\n", r.Version, r.Name, applied)
+		}
+		return nil
+	})
+}
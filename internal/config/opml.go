@@ -0,0 +1,264 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title       string `xml:"title"`
+		DateCreated string `xml:"dateCreated,omitempty"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []OPMLOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+// An outline is a feed when it has an xmlUrl, otherwise it's a folder
+type OPMLOutline struct {
+	Text        string        `xml:"text,attr"`
+	Title       string        `xml:"title,attr,omitempty"`
+	Type        string        `xml:"type,attr,omitempty"`
+	XMLURL      string        `xml:"xmlUrl,attr,omitempty"`
+	Description string        `xml:"description,attr,omitempty"`
+	Category    string        `xml:"category,attr,omitempty"`
+	Outlines    []OPMLOutline `xml:"outline"`
+}
+
+// Builds nested folder outlines from tags, a feed with several tags
+// shows up in each of its folders
+func buildOPML(follows []taggedFollow, title string) OPML {
+	doc := OPML{Version: "2.0"}
+	doc.Head.Title = title
+	doc.Head.DateCreated = time.Now().Format(time.RFC1123Z)
+
+	root := &OPMLOutline{}
+	for _, f := range follows {
+		feed := OPMLOutline{Text: f.Name, Title: f.Name, Type: "rss", XMLURL: f.Url, Description: f.Notes}
+		if len(f.Tags) == 0 {
+			root.Outlines = append(root.Outlines, feed)
+			continue
+		}
+		for _, tag := range f.Tags {
+			folder := root
+			for _, name := range strings.Split(tag, "/") {
+				folder = childFolder(folder, name)
+			}
+			folder.Outlines = append(folder.Outlines, feed)
+		}
+	}
+	sortOutlines(root.Outlines)
+	doc.Body.Outlines = root.Outlines
+	return doc
+}
+
+func childFolder(parent *OPMLOutline, name string) *OPMLOutline {
+	for i := range parent.Outlines {
+		if parent.Outlines[i].XMLURL == "" && parent.Outlines[i].Text == name {
+			return &parent.Outlines[i]
+		}
+	}
+	parent.Outlines = append(parent.Outlines, OPMLOutline{Text: name, Title: name})
+	return &parent.Outlines[len(parent.Outlines)-1]
+}
+
+// Folders first, then feeds, each by name
+func sortOutlines(outlines []OPMLOutline) {
+	sort.SliceStable(outlines, func(i, j int) bool {
+		fi, fj := outlines[i].XMLURL == "", outlines[j].XMLURL == ""
+		if fi != fj {
+			return fi
+		}
+		return strings.ToLower(outlines[i].Text) < strings.ToLower(outlines[j].Text)
+	})
+	for i := range outlines {
+		sortOutlines(outlines[i].Outlines)
+	}
+}
+
+func writeOPML(w io.Writer, doc OPML) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// A feed found in an OPML file along with the folders it sat in
+type opmlFeed struct {
+	Name  string
+	Url   string
+	Notes string
+	Tags  []string
+}
+
+func parseOPML(r io.Reader) ([]opmlFeed, error) {
+	var doc OPML
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+	err := dec.Decode(&doc)
+	if err != nil {
+		return nil, apperr.WrapKind(apperr.Validation, err, "unable to parse opml")
+	}
+
+	// feeds listed in several folders are merged into one entry with several tags
+	var feeds []opmlFeed
+	index := map[string]int{}
+	var walk func(outlines []OPMLOutline, path []string)
+	walk = func(outlines []OPMLOutline, path []string) {
+		for _, o := range outlines {
+			name := strings.TrimSpace(o.Text)
+			if name == "" {
+				name = strings.TrimSpace(o.Title)
+			}
+			if o.XMLURL == "" {
+				if name == "" {
+					walk(o.Outlines, path)
+				} else {
+					walk(o.Outlines, append(path[:len(path):len(path)], name))
+				}
+				continue
+			}
+
+			var tags []string
+			if len(path) > 0 {
+				tags = append(tags, strings.Join(path, "/"))
+			}
+			// some readers store folders in the category attribute instead
+			for _, c := range strings.Split(o.Category, ",") {
+				if tag, err := normalizeTag(c); err == nil {
+					tags = append(tags, tag)
+				}
+			}
+
+			i, ok := index[o.XMLURL]
+			if !ok {
+				if name == "" {
+					name = o.XMLURL
+				}
+				index[o.XMLURL] = len(feeds)
+				feeds = append(feeds, opmlFeed{Name: name, Url: o.XMLURL, Notes: strings.TrimSpace(o.Description)})
+				i = len(feeds) - 1
+			}
+			feeds[i].Tags = append(feeds[i].Tags, tags...)
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+	return feeds, nil
+}
+
+// Adds a feed from an OPML file if it's new, follows it and files it under its folders
+func (s State) importFeed(ctx context.Context, user database.User, f opmlFeed) (created bool, err error) {
+	// a login in the url is stored encrypted, as addfeed does
+	fedURL, cred, err := splitURLCredential(f.Url)
+	if err != nil {
+		return false, err
+	}
+	if f.Name == f.Url {
+		f.Name = fedURL
+	}
+	f.Url = fedURL
+
+	feed, err := s.dbq.GetFeed(ctx, f.Url)
+	if errors.Is(err, sql.ErrNoRows) {
+		if cred != nil {
+			if _, err := s.credentialKey(); err != nil {
+				return false, apperr.Wrap(err, "url contains credentials that can't be stored")
+			}
+		}
+		params := database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      f.Name,
+			Url:       f.Url,
+			UserID:    user.ID,
+		}
+		feed, err = s.dbq.CreateFeed(ctx, params)
+		if err != nil {
+			// feed names are unique, fall back to the url when the name is taken
+			params.Name = f.Url
+			feed, err = s.dbq.CreateFeed(ctx, params)
+		}
+		created = err == nil
+	}
+	if err != nil {
+		return false, apperr.WrapDB(err, "unable to add feed")
+	}
+	if created && cred != nil {
+		err = s.saveFeedCredential(ctx, feed.ID, *cred)
+		if err != nil {
+			return created, err
+		}
+	}
+
+	follow, err := s.dbq.GetFeedFollow(ctx, database.GetFeedFollowParams{
+		UserID: user.ID,
+		Url:    feed.Url,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		var inserted database.CreateFeedFollowRow
+		inserted, err = s.dbq.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+		})
+		follow.ID = inserted.ID
+	}
+	if err != nil {
+		return created, apperr.WrapDB(err, "unable to follow feed")
+	}
+
+	// the shared feed keeps its name, the file's name is kept for this user only
+	if feed.Name != f.Name {
+		err = s.dbq.RenameFeedFollow(ctx, database.RenameFeedFollowParams{
+			ID:          follow.ID,
+			DisplayName: f.Name,
+		})
+		if err != nil {
+			return created, apperr.WrapDB(err, "unable to rename feed")
+		}
+	}
+	if f.Notes != "" {
+		err = s.dbq.SetFeedFollowNotes(ctx, database.SetFeedFollowNotesParams{
+			ID:    follow.ID,
+			Notes: f.Notes,
+		})
+		if err != nil {
+			return created, apperr.WrapDB(err, "unable to save notes")
+		}
+	}
+
+	for _, tag := range f.Tags {
+		err = s.dbq.AddFollowTag(ctx, database.AddFollowTagParams{
+			FeedFollowID: follow.ID,
+			Name:         tag,
+		})
+		if err != nil {
+			return created, apperr.WrapDB(err, "unable to tag feed")
+		}
+	}
+	return created, nil
+}
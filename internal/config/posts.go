@@ -0,0 +1,245 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/ScooballyD/gator/internal/render"
+	"github.com/google/uuid"
+)
+
+// Query parameters that only track where a click came from
+var trackingParams = []string{"utm_", "fbclid", "gclid", "mc_cid", "mc_eid", "_hsenc", "_hsmi"}
+
+// Returns the key that identifies an item within its feed: the guid when there
+// is one, otherwise the link without tracking params, otherwise a hash of the
+// title and date
+func itemIdentity(itm RSSItem) string {
+	if guid := strings.TrimSpace(itm.GUID.Value); guid != "" {
+		return "guid:" + guid
+	}
+	if link := strings.TrimSpace(itm.Link); link != "" {
+		return "link:" + stripTracking(link)
+	}
+	sum := sha256.Sum256([]byte(itm.Title + "\n" + itm.PubDate))
+	return "hash:" + hex.EncodeToString(sum[:])
+}
+
+func stripTracking(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.RawQuery == "" {
+		return link
+	}
+
+	q := u.Query()
+	for name := range q {
+		for _, tp := range trackingParams {
+			if strings.HasPrefix(strings.ToLower(name), tp) {
+				q.Del(name)
+			}
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// Fills in the other feeds carrying each listed post, looked up for the whole
+// page at once by the post links without tracking params
+func (s State) addOtherFeeds(user database.User, rows []postRow, posts []database.Post, links []string) error {
+	if len(links) == 0 {
+		return nil
+	}
+	keys, err := json.Marshal(links)
+	if err != nil {
+		return apperr.Wrap(err, "unable to encode post links")
+	}
+	matches, err := s.dbq.GetFeedsForLinks(context.Background(), database.GetFeedsForLinksParams{
+		UserID:   user.ID,
+		LinkKeys: string(keys),
+	})
+	if err != nil {
+		return apperr.WrapDB(err, "unable to get other feeds for posts")
+	}
+	for i, pst := range posts {
+		link := stripTracking(pst.Url)
+		if link == "" {
+			continue
+		}
+		for _, m := range matches {
+			if m.LinkKey == link && m.FeedID != pst.FeedID && !slices.Contains(rows[i].AlsoIn, m.Name) {
+				rows[i].AlsoIn = append(rows[i].AlsoIn, m.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Stores the normalized link of posts saved before link keys existed, so
+// browse can match them against other feeds. Returns how many posts changed
+func fillLinkKeys(ctx context.Context, s *State) (int, error) {
+	posts, err := s.dbq.GetPostsWithoutLinkKey(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, pst := range posts {
+		err := s.dbq.SetPostLinkKey(ctx, database.SetPostLinkKeyParams{
+			ID:      pst.ID,
+			LinkKey: stripTracking(pst.Url),
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(posts), nil
+}
+
+// Re-keys posts whose link identity was stored with the raw url, as the
+// identity migration did, so they match the keys scraping gives new items.
+// Returns how many posts changed
+func normalizeLinkKeys(ctx context.Context, s *State) (int, error) {
+	posts, err := s.dbq.GetLinkKeyedPosts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, pst := range posts {
+		key := "link:" + stripTracking(strings.TrimPrefix(pst.IdentityKey, "link:"))
+		if key == pst.IdentityKey {
+			continue
+		}
+		// a post already stored under the new key keeps it, this one stays as it is
+		n, err := s.dbq.SetPostIdentityKey(ctx, database.SetPostIdentityKeyParams{
+			ID:          pst.ID,
+			IdentityKey: key,
+		})
+		if err != nil {
+			return changed, err
+		}
+		changed += int(n)
+	}
+	return changed, nil
+}
+
+// Finds a post in a feed the user follows from its id or an unambiguous
+// prefix of at least 8 characters, as printed by browse
+func (s State) findPost(ctx context.Context, user database.User, ref string) (database.Post, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if len(ref) < 8 {
+		return database.Post{}, apperr.New(apperr.Validation, "post id %q is too short: use at least 8 characters", ref)
+	}
+	if strings.Trim(ref, "0123456789abcdef-") != "" {
+		return database.Post{}, apperr.New(apperr.Validation, "post id %q can only have hex digits and dashes", ref)
+	}
+
+	posts, err := s.dbq.GetPostsByIDPrefix(ctx, database.GetPostsByIDPrefixParams{
+		UserID:   user.ID,
+		IDPrefix: ref,
+	})
+	if err != nil {
+		return database.Post{}, apperr.WrapDB(err, "unable to find post")
+	}
+	switch len(posts) {
+	case 0:
+		return database.Post{}, apperr.New(apperr.NotFound, "no post matching %v in the feeds you follow", ref)
+	case 1:
+		return posts[0], nil
+	}
+	return database.Post{}, apperr.New(apperr.Validation, "%v matches more than one post: use more of the id", ref)
+}
+
+// Stores the categories and enclosures of a freshly saved post
+func (s State) savePostMetadata(ctx context.Context, postID uuid.UUID, itm RSSItem) error {
+	for _, cat := range itm.Categories {
+		cat = strings.TrimSpace(cat)
+		if cat == "" {
+			continue
+		}
+		err := s.store.CreatePostCategory(ctx, database.CreatePostCategoryParams{
+			PostID: postID,
+			Name:   cat,
+		})
+		if err != nil {
+			return apperr.WrapDB(err, "unable to save category")
+		}
+	}
+
+	for _, enc := range itm.Enclosures {
+		if enc.URL == "" {
+			continue
+		}
+		// feeds often leave length empty or put junk in it, treat that as unknown
+		length, _ := strconv.ParseInt(strings.TrimSpace(enc.Length), 10, 64)
+		err := s.store.CreatePostEnclosure(ctx, database.CreatePostEnclosureParams{
+			ID:     uuid.New(),
+			PostID: postID,
+			Url:    enc.URL,
+			Type:   enc.Type,
+			Length: length,
+		})
+		if err != nil {
+			return apperr.WrapDB(err, "unable to save enclosure")
+		}
+	}
+	return nil
+}
+
+// Prints the metadata browse only shows in verbose mode
+func (s State) printPostDetails(ctx context.Context, pst database.Post) error {
+	if pst.Author != "" {
+		fmt.Printf("--author: %v\n", pst.Author)
+	}
+	if pst.Guid != "" {
+		fmt.Printf("--guid: %v (permalink: %v)\n", pst.Guid, pst.GuidIsPermalink)
+	}
+
+	cats, err := s.dbq.GetPostCategories(ctx, pst.ID)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to get categories")
+	}
+	if len(cats) > 0 {
+		fmt.Printf("--categories: %v\n", strings.Join(cats, ", "))
+	}
+
+	if pst.Comments != "" {
+		fmt.Printf("--comments: %v\n", pst.Comments)
+	}
+
+	encs, err := s.dbq.GetPostEnclosures(ctx, pst.ID)
+	if err != nil {
+		return apperr.WrapDB(err, "unable to get enclosures")
+	}
+	for _, enc := range encs {
+		size := "unknown size"
+		if enc.Length > 0 {
+			size = formatBytes(enc.Length)
+		}
+		fmt.Printf("--enclosure: %v (%v, %v)\n", enc.Url, enc.Type, size)
+	}
+
+	if pst.ContentEncoded != "" {
+		fmt.Printf("--content:\n%v\n", render.Text(pst.ContentEncoded, render.Options{
+			Width:  terminalWidth(),
+			Indent: "    ",
+		}))
+	}
+	return nil
+}
+
+// Width to wrap rendered text at, taken from $COLUMNS when the shell exports it
+func terminalWidth() int {
+	cols, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || cols < 40 {
+		return 80
+	}
+	return cols
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+)
+
+type pruneOptions struct {
+	days       int
+	maxPosts   int
+	keepUnread bool
+	dryRun     bool
+}
+
+// Registers the retention flags shared by prune and agg,
+// defaults come from the config file
+func pruneFlags(s *State, fs *flag.FlagSet) {
+	fs.Int("days", s.point.Retention_days, "delete posts published more than this many days ago, 0 keeps them")
+	fs.Int("keep", s.point.Retention_max_posts, "keep only this many of the newest posts per feed, 0 keeps all")
+	fs.Bool("keep-unread", s.point.keepUnread(), "never delete posts a follower hasn't read, --keep-unread=false deletes them too")
+}
+
+// Reads the flags registered by pruneFlags, falling back on the config
+// file, which may have changed since the flags were parsed
+func pruneOptionsFrom(s *State, cmd Command) (pruneOptions, error) {
+	opts := pruneOptions{
+		days:       s.point.Retention_days,
+		maxPosts:   s.point.Retention_max_posts,
+		keepUnread: s.point.keepUnread(),
+	}
+	if cmd.hasFlag("days") {
+		opts.days = cmd.intFlag("days")
+	}
+	if cmd.hasFlag("keep") {
+		opts.maxPosts = cmd.intFlag("keep")
+	}
+	if cmd.hasFlag("keep-unread") {
+		opts.keepUnread = cmd.boolFlag("keep-unread")
+	}
+	if opts.days < 0 || opts.maxPosts < 0 {
+		return pruneOptions{}, apperr.New(apperr.Validation, "--days and --keep can't be negative")
+	}
+	return opts, nil
+}
+
+// Deletes posts outside each feed's retention window, a feed's own settings
+// override the global ones and a feed set to 0 keeps its posts forever,
+// starred posts are always kept
+func (s State) prunePosts(ctx context.Context, opts pruneOptions) (int, error) {
+	feeds, err := s.dbq.GetFeeds(ctx)
+	if err != nil {
+		return 0, apperr.WrapDB(err, "unable to retrieve feeds")
+	}
+
+	total := 0
+	for _, feed := range feeds {
+		days, maxPosts := opts.days, opts.maxPosts
+		if feed.RetentionDays.Valid {
+			days = int(feed.RetentionDays.Int32)
+		}
+		if feed.RetentionMaxPosts.Valid {
+			maxPosts = int(feed.RetentionMaxPosts.Int32)
+		}
+		if days == 0 && maxPosts == 0 {
+			continue
+		}
+
+		cutoff := time.Time{}
+		if days > 0 {
+			cutoff = time.Now().AddDate(0, 0, -days)
+		}
+		keepNewest := int64(math.MaxInt64)
+		if maxPosts > 0 {
+			keepNewest = int64(maxPosts)
+		}
+
+		posts, err := s.dbq.GetPrunablePostsForFeed(ctx, database.GetPrunablePostsForFeedParams{
+			FeedID:     feed.ID,
+			Cutoff:     cutoff,
+			KeepNewest: keepNewest,
+			KeepUnread: opts.keepUnread,
+		})
+		if err != nil {
+			return total, apperr.WrapDB(err, "unable to find posts to prune in %v", feed.Name)
+		}
+
+		for _, pst := range posts {
+			if opts.dryRun {
+				fmt.Printf("%v: %v (%v)\n", feed.Name, pst.Title, pst.PublishedAt.Format(time.DateOnly))
+				total++
+				continue
+			}
+			err = s.dbq.DeletePost(ctx, pst.ID)
+			if err != nil {
+				return total, apperr.WrapDB(err, "unable to delete post")
+			}
+			total++
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+)
+
+// Fetches the given feeds once, through the daemon when it's running so
+// the two don't fetch the same feed at the same time
+func HandlerRefresh(s *State, cmd Command, user database.User) error {
+	concurrency := cmd.intFlag("concurrency")
+	if concurrency < 1 {
+		return apperr.New(apperr.Validation, "concurrency must be at least 1")
+	}
+	urls, err := refreshURLs(s, cmd)
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		fmt.Println("No feeds to refresh")
+		return nil
+	}
+
+	resp, err := s.daemonRequest(controlRequest{Command: "refresh", Urls: urls, Concurrency: concurrency})
+	rows := resp.Results
+	if errors.Is(err, errDaemonNotRunning) {
+		rows = refreshFeeds(context.Background(), s, urls, concurrency, func(ctx context.Context, feed database.Feed) (int, error) {
+			return scrapeFeed(ctx, s, feed)
+		})
+	} else if err != nil {
+		return err
+	}
+
+	failed, added := 0, 0
+	for _, r := range rows {
+		added += r.New
+		if r.Error != "" {
+			failed++
+		}
+	}
+	err = s.render(rows, func() error {
+		for _, r := range rows {
+			if r.Error != "" {
+				fmt.Printf("%v: %v\n", r.Feed, r.Error)
+				continue
+			}
+			fmt.Printf("%v: %v new posts\n", r.Feed, r.New)
+		}
+		fmt.Printf("Refreshed %v feeds, %v new posts\n", len(rows)-failed, added)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return apperr.New(apperr.Network, "%v of %v feeds failed to refresh", failed, len(rows))
+	}
+	return nil
+}
+
+// Urls of the feeds picked by the arguments, --mine or --all,
+// each only once however often it was given
+func refreshURLs(s *State, cmd Command) ([]string, error) {
+	mine, all := cmd.boolFlag("mine"), cmd.boolFlag("all")
+	picked := 0
+	for _, given := range []bool{len(cmd.Arguments) > 0, mine, all} {
+		if given {
+			picked++
+		}
+	}
+	if picked != 1 {
+		return nil, apperr.New(apperr.Validation, "refresh takes feed urls, --mine or --all\nusage: gator refresh [url...] | --mine | --all")
+	}
+
+	ctx := context.Background()
+	var urls []string
+	switch {
+	case mine:
+		user, err := s.dbq.GetUser(ctx, s.point.Current_user_name)
+		if err != nil {
+			return nil, apperr.WrapDB(err, "unable to find current user")
+		}
+		follows, err := s.dbq.GetFeedFollowsForUser(ctx, user.ID)
+		if err != nil {
+			return nil, apperr.WrapDB(err, "unable to get follows")
+		}
+		for _, f := range follows {
+			urls = append(urls, f.FeedUrl)
+		}
+	case all:
+		feeds, err := s.dbq.GetFeeds(ctx)
+		if err != nil {
+			return nil, apperr.WrapDB(err, "unable to retrieve feeds")
+		}
+		for _, f := range feeds {
+			urls = append(urls, f.Url)
+		}
+	default:
+		seen := map[string]bool{}
+		for _, url := range cmd.Arguments {
+			if !seen[url] {
+				seen[url] = true
+				urls = append(urls, url)
+			}
+		}
+	}
+	return urls, nil
+}
+
+// Fetches the feeds with the given urls, at most concurrency at once,
+// the rows come back in the order of urls
+func refreshFeeds(ctx context.Context, s *State, urls []string, concurrency int, scrape func(context.Context, database.Feed) (int, error)) []refreshRow {
+	rows := make([]refreshRow, len(urls))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, url := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, url string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			row := refreshRow{Feed: RedactURL(url), Url: RedactURL(url)}
+			n, err := refreshFeed(ctx, s, url, &row, scrape)
+			row.New = n
+			if err != nil {
+				row.Error = apperr.Message(err, false)
+			}
+			rows[i] = row
+		}(i, url)
+	}
+	wg.Wait()
+	return rows
+}
+
+func refreshFeed(ctx context.Context, s *State, url string, row *refreshRow, scrape func(context.Context, database.Feed) (int, error)) (int, error) {
+	feed, err := s.store.GetFeed(ctx, url)
+	if err != nil {
+		return 0, apperr.WrapDB(err, "unable to find feed")
+	}
+	row.Feed = feed.Name
+	feed, err = s.store.MarkFeedFetched(ctx, feed.ID)
+	if err != nil {
+		return 0, apperr.WrapDB(err, "unable to mark feed")
+	}
+	return scrape(ctx, feed)
+}
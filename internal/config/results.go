@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/output"
+)
+
+// Output format that keeps each command's own layout
+const formatText = "text"
+
+// Rows returned by the listing commands, the json tags name the columns
+// and keys in every output format
+
+type userRow struct {
+	Name    string `json:"name"`
+	Role    string `json:"role,omitempty"`
+	Current bool   `json:"current"`
+}
+
+type feedRow struct {
+	Name  string `json:"name"`
+	Url   string `json:"url"`
+	Owner string `json:"owner"`
+}
+
+type followRow struct {
+	Name  string   `json:"name"`
+	Url   string   `json:"url"`
+	Tags  []string `json:"tags"`
+	Notes string   `json:"notes"`
+}
+
+type postRow struct {
+	ID          string    `json:"id"`
+	Feed        string    `json:"feed"`
+	Title       string    `json:"title"`
+	PublishedAt time.Time `json:"published_at"`
+	Url         string    `json:"url"`
+	Author      string    `json:"author"`
+	Starred     bool      `json:"starred"`
+	Read        bool      `json:"read"`
+	Hidden      bool      `json:"hidden"`
+	AlsoIn      []string  `json:"also_in"`
+	Description string    `json:"description"`
+}
+
+type episodeRow struct {
+	PostID       string    `json:"post_id"`
+	Feed         string    `json:"feed"`
+	Title        string    `json:"title"`
+	PublishedAt  time.Time `json:"published_at"`
+	Url          string    `json:"url"`
+	Type         string    `json:"type"`
+	Bytes        int64     `json:"bytes"`
+	DownloadedTo string    `json:"downloaded_to"`
+}
+
+type feedStatsRow struct {
+	Feed         string `json:"feed"`
+	Fetches      int64  `json:"fetches"`
+	WireBytes    int64  `json:"wire_bytes"`
+	DecodedBytes int64  `json:"decoded_bytes"`
+}
+
+type filterRow struct {
+	ID          string `json:"id"`
+	Action      string `json:"action"`
+	TitleRegex  string `json:"title_regex"`
+	AuthorRegex string `json:"author_regex"`
+	Keyword     string `json:"keyword"`
+	Feed        string `json:"feed"`
+}
+
+type refreshRow struct {
+	Feed  string `json:"feed"`
+	Url   string `json:"url"`
+	New   int    `json:"new"`
+	Error string `json:"error"`
+}
+
+type migrationRow struct {
+	Version   int64     `json:"version"`
+	Name      string    `json:"name"`
+	Applied   bool      `json:"applied"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Whether --debug was given after the command name, errors then show their full cause
+func (s *State) Debug() bool {
+	return s.debug
+}
+
+// Sets the format listings are printed in, text keeps each command's own layout
+func (s *State) SetOutput(format string) error {
+	if format != formatText && output.Valid(format) != nil {
+		return apperr.New(apperr.Validation,
+			"unknown output format %q, expected one of %v", format, strings.Join(outputFormats(), ", "))
+	}
+	s.output = format
+	return nil
+}
+
+// Every format --output accepts
+func outputFormats() []string {
+	return append([]string{formatText}, output.Formats...)
+}
+
+// Prints rows in the chosen output format, text falls back to a table
+// for commands that don't have a layout of their own
+func (s State) render(rows any, text func() error) error {
+	format := s.output
+	if format == "" || format == formatText {
+		if text != nil {
+			return text()
+		}
+		format = output.Table
+	}
+	return output.Render(os.Stdout, format, rows)
+}
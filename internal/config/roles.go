@@ -0,0 +1,35 @@
+package config
+
+import (
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+)
+
+const (
+	RoleAdmin    = "admin"
+	RoleMember   = "member"
+	RoleReadOnly = "read-only"
+)
+
+// Each role can do everything the roles below it can
+var roleRanks = map[string]int{
+	RoleReadOnly: 1,
+	RoleMember:   2,
+	RoleAdmin:    3,
+}
+
+func hasRole(user database.User, role string) bool {
+	return roleRanks[user.Role] >= roleRanks[role]
+}
+
+// Whether user may change feed's settings, its owner and admins can
+func canManageFeed(user database.User, feed database.Feed) bool {
+	return feed.UserID == user.ID || hasRole(user, RoleAdmin)
+}
+
+func validRole(role string) error {
+	if _, ok := roleRanks[role]; !ok {
+		return apperr.New(apperr.Validation, "unknown role %q, expected %v, %v or %v", role, RoleAdmin, RoleMember, RoleReadOnly)
+	}
+	return nil
+}
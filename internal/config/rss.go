@@ -3,13 +3,32 @@ package config
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/apperr"
 )
 
+// Default cap on a feed response body, "max_feed_bytes" in the config overrides it
+const defaultMaxFeedBytes = 10 << 20
+
+// How long a feed or article page gets to arrive in full, enclosures
+// can take longer and are only bound by the transport's timeouts
+const fetchTimeout = time.Minute
+
+// How long to wait for a server to start answering
+const responseHeaderTimeout = 30 * time.Second
+
+var errFeedTooLarge = errors.New("feed exceeds size limit")
+
 type RSSFeed struct {
+	XMLName xml.Name
 	Channel struct {
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
@@ -19,40 +38,191 @@ type RSSFeed struct {
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title          string         `xml:"title"`
+	Link           string         `xml:"link"`
+	Description    string         `xml:"description"`
+	PubDate        string         `xml:"pubDate"`
+	GUID           RSSGUID        `xml:"guid"`
+	Author         string         `xml:"author"`
+	Creator        string         `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories     []string       `xml:"category"`
+	ContentEncoded string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Comments       string         `xml:"comments"`
+	Enclosures     []RSSEnclosure `xml:"enclosure"`
+}
+
+type RSSGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
 }
 
-type Client struct {
-	httpClient http.Client
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
 }
 
-func (s State) FetchFeed(ctx context.Context, fedURL string) (*RSSFeed, error) {
+// Per the RSS spec a guid is a permalink unless it says otherwise
+func (g RSSGUID) PermaLink() bool {
+	return g.Value != "" && !strings.EqualFold(strings.TrimSpace(g.IsPermaLink), "false")
+}
+
+// Date formats seen in pubDate, RSS asks for RFC 822 but feeds drop
+// the day name, the seconds or the leading zero, or use RFC 3339
+var pubDateLayouts = []string{
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 06 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+}
+
+// Parses the item's pubDate, false when it's missing or in no format we know
+func (itm RSSItem) Published() (time.Time, bool) {
+	value := strings.TrimSpace(itm.PubDate)
+	for _, layout := range pubDateLayouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Returns the item's author, falling back to dc:creator
+func (itm RSSItem) AuthorName() string {
+	if itm.Author != "" {
+		return itm.Author
+	}
+	return itm.Creator
+}
+
+// Reader that fails once more than limit bytes have been read,
+// unlike io.LimitReader a truncated feed can't pass for a complete one
+type cappedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if c.read > c.limit {
+		return n, fmt.Errorf("%w of %v bytes", errFeedTooLarge, c.limit)
+	}
+	return n, err
+}
+
+// Fetches and parses an RSS feed, the returned stats are filled in
+// as far as the request got even when an error is returned
+func (s State) FetchFeed(ctx context.Context, fedURL string) (feed *RSSFeed, stats FetchStats, err error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
 	req, err := http.NewRequestWithContext(ctx, "GET", fedURL, nil)
 	if err != nil {
-		return &RSSFeed{}, fmt.Errorf("unable to send request: %v", err)
+		return &RSSFeed{}, stats, apperr.Wrap(err, "unable to send request")
 	}
 
-	clnt := Client{
-		httpClient: http.Client{},
-	}
 	req.Header.Add("User-Agent", "gator")
+	req.Header.Add("Accept", "application/rss+xml, application/xml;q=0.9, text/xml;q=0.8")
+	// setting this ourselves turns off the transport's transparent gzip handling
+	req.Header.Add("Accept-Encoding", acceptEncoding)
+
+	cred, err := s.feedCredential(ctx, fedURL)
+	if err != nil {
+		return &RSSFeed{}, stats, err
+	}
+	if cred != nil {
+		cred.apply(req)
+	}
 
-	resp, err := clnt.httpClient.Do(req)
+	resp, err := s.http.Do(req)
 	if err != nil {
-		return &RSSFeed{}, fmt.Errorf("response error: %v", err)
+		return &RSSFeed{}, stats, apperr.Wrap(redactError(err), "response error")
 	}
 
 	defer resp.Body.Close()
 
+	stats.StatusCode = resp.StatusCode
+	stats.Encoding = resp.Header.Get("Content-Encoding")
+	s.log.Debug("feed responded",
+		"url", RedactURL(fedURL),
+		"status", resp.StatusCode,
+		"content_type", resp.Header.Get("Content-Type"),
+		"encoding", stats.Encoding,
+		"content_length", resp.ContentLength)
+	if resp.StatusCode != http.StatusOK {
+		return &RSSFeed{}, stats, apperr.New(apperr.Network, "unexpected response status: %v", resp.Status)
+	}
+	charset, err := checkContentType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return &RSSFeed{}, stats, err
+	}
+
+	limit := s.point.Max_feed_bytes
+	if limit <= 0 {
+		limit = defaultMaxFeedBytes
+	}
+	if resp.ContentLength > limit {
+		return &RSSFeed{}, stats, fmt.Errorf("%w of %v bytes: server reports %v", errFeedTooLarge, limit, resp.ContentLength)
+	}
+
+	wire := countingReader{r: resp.Body, count: &stats.WireBytes}
+	decoded, err := decodeBody(stats.Encoding, wire)
+	if err != nil {
+		return &RSSFeed{}, stats, err
+	}
+	defer decoded.Close()
+
+	// the limit applies after decoding so a small compressed body can't expand without bound
+	capped := &cappedReader{r: decoded, limit: limit}
+	defer func() {
+		stats.DecodedBytes = capped.read
+	}()
+
+	var body io.Reader = capped
+	readCharset := charsetReader
+	if charset != "" {
+		// the charset from the http header wins over the xml declaration
+		body, err = charsetReader(charset, body)
+		if err != nil {
+			return &RSSFeed{}, stats, err
+		}
+		readCharset = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	}
+
 	newRSSFeed := RSSFeed{}
-	dat, err := io.ReadAll(resp.Body)
+	dec := xml.NewDecoder(body)
+	dec.CharsetReader = readCharset
+	err = dec.Decode(&newRSSFeed)
 	if err != nil {
-		return &RSSFeed{}, fmt.Errorf("read error: %v", err)
+		if errors.Is(err, errFeedTooLarge) {
+			return &RSSFeed{}, stats, err
+		}
+		stats.ParseFailed = true
+		return &RSSFeed{}, stats, apperr.WrapKind(apperr.Validation, err, "unable to parse feed")
+	}
+	// count anything trailing the root element so the totals match the transfer
+	io.Copy(io.Discard, capped)
+
+	// only RSS 2.0, Atom has <feed> and RSS 1.0 <rdf:RDF> at the root
+	if newRSSFeed.XMLName.Local != "rss" {
+		stats.ParseFailed = true
+		return &RSSFeed{}, stats, apperr.New(apperr.Validation,
+			"unsupported feed format: root element is <%v>, only RSS 2.0 feeds are supported", newRSSFeed.XMLName.Local)
+	}
+	if newRSSFeed.Channel.Title == "" && len(newRSSFeed.Channel.Item) == 0 {
+		stats.ParseFailed = true
+		return &RSSFeed{}, stats, apperr.New(apperr.Validation, "unable to parse feed: no channel found")
 	}
-	xml.Unmarshal(dat, &newRSSFeed)
 
 	newRSSFeed.Channel.Title = html.UnescapeString(newRSSFeed.Channel.Title)
 	newRSSFeed.Channel.Description = html.UnescapeString(newRSSFeed.Channel.Description)
@@ -61,5 +231,22 @@ func (s State) FetchFeed(ctx context.Context, fedURL string) (*RSSFeed, error) {
 		newRSSFeed.Channel.Item[i].Description = html.UnescapeString(itm.Description)
 	}
 
-	return &newRSSFeed, nil
+	return &newRSSFeed, stats, nil
+}
+
+// Rejects responses that can't be an RSS document, like html error pages,
+// and returns the charset named by the header if there is one
+func checkContentType(header string) (string, error) {
+	if header == "" {
+		return "", nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", apperr.WrapKind(apperr.Network, err, "invalid content type %q", header)
+	}
+	if mediaType != "application/xml" && mediaType != "text/xml" && !strings.HasSuffix(mediaType, "+xml") {
+		return "", apperr.New(apperr.Network, "unexpected content type %v: expected an XML feed", mediaType)
+	}
+	return params["charset"], nil
 }
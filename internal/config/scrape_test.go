@@ -0,0 +1,524 @@
+package config
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// State wired to an in-memory store, the test server's client and a fixed clock
+func newTestState(t *testing.T, srv *httptest.Server) (*State, *memStore) {
+	t.Helper()
+	c := &testClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store := newMemStore(c)
+	s := &State{
+		point:   &Config{},
+		output:  formatText,
+		store:   store,
+		http:    srv.Client(),
+		clock:   c,
+		log:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics: newAggMetrics(),
+	}
+	return s, store
+}
+
+// Serves the files in testdata/feeds:
+//
+//	/feeds/{name}?type=...&gzip=1  the fixture, as application/rss+xml unless type says otherwise
+//	/status/{code}                 an empty response with that status
+//	/hang                          nothing until the client gives up
+func feedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feeds/{name}", func(w http.ResponseWriter, r *http.Request) {
+		body, err := os.ReadFile(filepath.Join("testdata", "feeds", r.PathValue("name")))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		contentType := "application/rss+xml"
+		if ct := r.URL.Query().Get("type"); ct != "" {
+			contentType = ct
+		}
+		w.Header().Set("Content-Type", contentType)
+		if r.URL.Query().Get("gzip") == "1" {
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			defer zw.Close()
+			zw.Write(body)
+			return
+		}
+		w.Write(body)
+	})
+	mux.HandleFunc("/status/{code}", func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(r.PathValue("code"))
+		w.WriteHeader(code)
+	})
+	mux.HandleFunc("/hang", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func metricsText(t *testing.T, s *State) string {
+	t.Helper()
+	var b strings.Builder
+	err := s.metrics.registry.Write(&b)
+	if err != nil {
+		t.Fatalf("writing metrics: %v", err)
+	}
+	return b.String()
+}
+
+func TestScrapeFeedVariants(t *testing.T) {
+	srv := feedServer(t)
+	tests := []struct {
+		name   string
+		path   string
+		titles []string
+	}{
+		{
+			name:   "rss 2.0",
+			path:   "/feeds/rss2.xml",
+			titles: []string{"Fish & chips", "Episode 1", "No guid"},
+		},
+		{
+			name:   "gzip encoded",
+			path:   "/feeds/rss2.xml?gzip=1",
+			titles: []string{"Fish & chips", "Episode 1", "No guid"},
+		},
+		{
+			name:   "text/xml with a charset",
+			path:   "/feeds/rss2.xml?type=text/xml;+charset=utf-8",
+			titles: []string{"Fish & chips", "Episode 1", "No guid"},
+		},
+		{
+			name:   "latin-1 from the xml declaration",
+			path:   "/feeds/latin1.xml",
+			titles: []string{"Crème brûlée"},
+		},
+		{
+			name:   "latin-1 from the content type",
+			path:   "/feeds/latin1.xml?type=application/rss%2Bxml;+charset=ISO-8859-1",
+			titles: []string{"Crème brûlée"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store := newTestState(t, srv)
+			feed := store.addFeed("test", srv.URL+tt.path)
+
+			saved, err := scrapeFeed(context.Background(), s, feed)
+			if err != nil {
+				t.Fatalf("scrapeFeed: %v", err)
+			}
+			if saved != len(tt.titles) {
+				t.Errorf("saved %v posts, want %v", saved, len(tt.titles))
+			}
+			var titles []string
+			for _, p := range store.postsFor(feed.ID) {
+				titles = append(titles, p.Title)
+			}
+			if strings.Join(titles, "|") != strings.Join(tt.titles, "|") {
+				t.Errorf("titles = %q, want %q", titles, tt.titles)
+			}
+		})
+	}
+}
+
+func TestScrapeFeedStoresItemDetails(t *testing.T) {
+	srv := feedServer(t)
+	s, store := newTestState(t, srv)
+	feed := store.addFeed("test", srv.URL+"/feeds/rss2.xml?gzip=1")
+
+	_, err := scrapeFeed(context.Background(), s, feed)
+	if err != nil {
+		t.Fatalf("scrapeFeed: %v", err)
+	}
+	posts := store.postsFor(feed.ID)
+	if len(posts) != 3 {
+		t.Fatalf("got %v posts, want 3", len(posts))
+	}
+
+	fish := posts[0]
+	if fish.Guid != "tag:example.com,2006:fish" || fish.GuidIsPermalink {
+		t.Errorf("guid = %q permalink %v, want a non-permalink tag guid", fish.Guid, fish.GuidIsPermalink)
+	}
+	if fish.Author != "Alice" {
+		t.Errorf("author = %q, want the dc:creator Alice", fish.Author)
+	}
+	if fish.ContentEncoded != "<p>Fried <b>fish</b></p>" {
+		t.Errorf("content:encoded = %q", fish.ContentEncoded)
+	}
+	wantDate := time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)
+	if !fish.PublishedAt.Equal(wantDate) {
+		t.Errorf("published at %v, want %v", fish.PublishedAt, wantDate)
+	}
+	if got := strings.Join(store.categories[fish.ID], ","); got != "food,uk" {
+		t.Errorf("categories = %q, want food,uk", got)
+	}
+
+	episode := posts[1]
+	if !episode.GuidIsPermalink || episode.Author != "bob@example.com (Bob)" {
+		t.Errorf("episode guid permalink %v author %q", episode.GuidIsPermalink, episode.Author)
+	}
+	if len(store.enclosures) != 1 || store.enclosures[0].PostID != episode.ID || store.enclosures[0].Length != 1234 {
+		t.Errorf("enclosures = %+v, want the episode's mp3 of 1234 bytes", store.enclosures)
+	}
+
+	if key := posts[2].IdentityKey; key != "link:https://example.com/no-guid" {
+		t.Errorf("identity of an item without guid = %q, want its link without tracking", key)
+	}
+
+	if len(store.fetches) != 1 {
+		t.Fatalf("recorded %v fetches, want 1", len(store.fetches))
+	}
+	fetch := store.fetches[0]
+	if fetch.StatusCode != 200 || fetch.ContentEncoding != "gzip" || fetch.WireBytes >= fetch.DecodedBytes {
+		t.Errorf("fetch = %+v, want a gzip 200 smaller on the wire than decoded", fetch)
+	}
+	if !fetch.FetchedAt.Equal(s.clock.Now()) {
+		t.Errorf("fetched at %v, want the clock's %v", fetch.FetchedAt, s.clock.Now())
+	}
+}
+
+func TestScrapeFeedBadDate(t *testing.T) {
+	srv := feedServer(t)
+	s, store := newTestState(t, srv)
+	feed := store.addFeed("test", srv.URL+"/feeds/bad_date.xml")
+
+	saved, err := scrapeFeed(context.Background(), s, feed)
+	if err != nil {
+		t.Fatalf("scrapeFeed: %v", err)
+	}
+	if saved != 4 {
+		t.Errorf("saved %v posts, want all 4 despite the bad date", saved)
+	}
+	want := map[string]time.Time{
+		"Fine":            time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC),
+		"When?":           s.clock.Now(),
+		"No leading zero": time.Date(2006, 1, 3, 15, 4, 5, 0, time.UTC),
+		"Atom style":      time.Date(2006, 1, 4, 15, 4, 5, 0, time.UTC),
+	}
+	for _, p := range store.postsFor(feed.ID) {
+		if !p.PublishedAt.Equal(want[p.Title]) {
+			t.Errorf("%q published at %v, want %v", p.Title, p.PublishedAt, want[p.Title])
+		}
+	}
+	if !strings.Contains(metricsText(t, s), "gator_feed_parse_failures_total 1\n") {
+		t.Errorf("parse failure not counted")
+	}
+}
+
+func TestScrapeFeedDuplicates(t *testing.T) {
+	srv := feedServer(t)
+	s, store := newTestState(t, srv)
+	feed := store.addFeed("test", srv.URL+"/feeds/duplicates.xml")
+
+	saved, err := scrapeFeed(context.Background(), s, feed)
+	if err != nil {
+		t.Fatalf("first scrape: %v", err)
+	}
+	if saved != 2 {
+		t.Errorf("first scrape saved %v posts, want 2", saved)
+	}
+
+	saved, err = scrapeFeed(context.Background(), s, feed)
+	if err != nil {
+		t.Fatalf("second scrape: %v", err)
+	}
+	if saved != 0 {
+		t.Errorf("second scrape saved %v posts, want 0", saved)
+	}
+
+	var titles []string
+	for _, p := range store.postsFor(feed.ID) {
+		titles = append(titles, p.Title)
+	}
+	if got := strings.Join(titles, "|"); got != "Original|Tracked link" {
+		t.Errorf("titles = %q, want the first of each duplicate", got)
+	}
+	if !strings.Contains(metricsText(t, s), "gator_posts_inserted_total 2\n") {
+		t.Errorf("posts inserted not counted once each")
+	}
+}
+
+func TestScrapeFeedFailures(t *testing.T) {
+	srv := feedServer(t)
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		maxBytes int64
+		kind     apperr.Kind
+		contains string
+		// status of the recorded fetch, 0 when none should be recorded
+		status int32
+	}{
+		{
+			name:     "server error",
+			url:      srv.URL + "/status/500",
+			kind:     apperr.Network,
+			contains: "unexpected response status: 500",
+			status:   500,
+		},
+		{
+			name:     "not modified is not a feed",
+			url:      srv.URL + "/status/304",
+			kind:     apperr.Network,
+			contains: "unexpected response status: 304",
+			status:   304,
+		},
+		{
+			name:     "html error page",
+			url:      srv.URL + "/feeds/rss2.xml?type=text/html",
+			kind:     apperr.Network,
+			contains: "unexpected content type text/html",
+			status:   200,
+		},
+		{
+			name:     "atom",
+			url:      srv.URL + "/feeds/atom.xml",
+			kind:     apperr.Validation,
+			contains: "root element is <feed>",
+			status:   200,
+		},
+		{
+			name:     "no channel",
+			url:      srv.URL + "/feeds/empty.xml",
+			kind:     apperr.Validation,
+			contains: "no channel found",
+			status:   200,
+		},
+		{
+			name:     "too large",
+			url:      srv.URL + "/feeds/rss2.xml",
+			maxBytes: 100,
+			contains: "feed exceeds size limit",
+			status:   200,
+		},
+		{
+			name:     "unreachable",
+			url:      gone.URL + "/feeds/rss2.xml",
+			kind:     apperr.Network,
+			contains: "response error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store := newTestState(t, srv)
+			s.point.Max_feed_bytes = tt.maxBytes
+			feed := store.addFeed("test", tt.url)
+
+			saved, err := scrapeFeed(context.Background(), s, feed)
+			if err == nil {
+				t.Fatalf("scrapeFeed succeeded, want an error containing %q", tt.contains)
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.contains)
+			}
+			if tt.kind != apperr.Internal && apperr.KindOf(err) != tt.kind {
+				t.Errorf("kind = %v, want %v", apperr.KindOf(err), tt.kind)
+			}
+			if saved != 0 || len(store.posts) != 0 {
+				t.Errorf("saved %v posts, want none", saved)
+			}
+
+			switch {
+			case tt.status == 0 && len(store.fetches) != 0:
+				t.Errorf("recorded %+v, want no fetch", store.fetches)
+			case tt.status != 0 && (len(store.fetches) != 1 || store.fetches[0].StatusCode != tt.status):
+				t.Errorf("recorded %+v, want one fetch with status %v", store.fetches, tt.status)
+			}
+		})
+	}
+}
+
+func TestScrapeFeedCancelled(t *testing.T) {
+	srv := feedServer(t)
+	s, store := newTestState(t, srv)
+	feed := store.addFeed("test", srv.URL+"/hang")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := scrapeFeed(ctx, s, feed)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("scrapeFeed kept waiting on the feed after ctx was cancelled")
+	}
+}
+
+func TestScrapeFeedBrokenStore(t *testing.T) {
+	srv := feedServer(t)
+	s, store := newTestState(t, srv)
+	feed := store.addFeed("test", srv.URL+"/feeds/rss2.xml")
+	store.err = errors.New("connection refused")
+
+	_, err := scrapeFeed(context.Background(), s, feed)
+	if apperr.KindOf(err) != apperr.DB {
+		t.Fatalf("err = %v (%v), want a database error so agg stops", err, apperr.KindOf(err))
+	}
+}
+
+func TestScrapeFeedAppliesFilters(t *testing.T) {
+	srv := feedServer(t)
+	s, store := newTestState(t, srv)
+	feed := store.addFeed("test", srv.URL+"/feeds/rss2.xml")
+	other := store.addFeed("other", srv.URL+"/feeds/latin1.xml")
+	store.rules = []database.FilterRule{
+		{ID: uuid.New(), UserID: uuid.New(), TitlePattern: "^Episode", Action: actionHide},
+		{ID: uuid.New(), UserID: uuid.New(), Keyword: "FRIED", Action: actionStar},
+		{ID: uuid.New(), UserID: uuid.New(), FeedID: uuid.NullUUID{UUID: other.ID, Valid: true}, Action: actionMarkRead},
+	}
+
+	_, err := scrapeFeed(context.Background(), s, feed)
+	if err != nil {
+		t.Fatalf("scrapeFeed: %v", err)
+	}
+	posts := store.postsFor(feed.ID)
+	if !store.starred[posts[0].ID] || store.hidden[posts[0].ID] {
+		t.Errorf("fish post should be starred by keyword and not hidden")
+	}
+	if !store.hidden[posts[1].ID] || store.starred[posts[1].ID] {
+		t.Errorf("episode post should be hidden by title and not starred")
+	}
+	if len(store.read) != 0 {
+		t.Errorf("a rule for another feed marked %v posts read", len(store.read))
+	}
+}
+
+func TestCompileRulesLogsBrokenRules(t *testing.T) {
+	var logged strings.Builder
+	log := slog.New(slog.NewTextHandler(&logged, nil))
+	rules := []database.FilterRule{
+		{ID: uuid.New(), TitlePattern: "("},
+		{ID: uuid.New(), Keyword: "go"},
+	}
+	compiled := compileRules(log, rules)
+	if len(compiled) != 1 || compiled[0].ID != rules[1].ID {
+		t.Errorf("compileRules() = %+v, want only the rule that compiles", compiled)
+	}
+	if !strings.Contains(logged.String(), "skipping filter") || !strings.Contains(logged.String(), rules[0].ID.String()) {
+		t.Errorf("logged %q, want a warning naming the broken rule", logged.String())
+	}
+}
+
+func TestNextFeed(t *testing.T) {
+	srv := feedServer(t)
+	s, store := newTestState(t, srv)
+
+	_, found, err := nextFeed(context.Background(), s)
+	if err != nil || found {
+		t.Fatalf("nextFeed on no feeds = found %v, err %v, want nothing", found, err)
+	}
+
+	first := store.addFeed("first", srv.URL+"/feeds/rss2.xml")
+	second := store.addFeed("second", srv.URL+"/feeds/latin1.xml")
+	feed, found, err := nextFeed(context.Background(), s)
+	if err != nil || !found || feed.ID != first.ID {
+		t.Fatalf("nextFeed = %v, %v, %v, want the first feed", feed.Name, found, err)
+	}
+	if !feed.LastFetchedAt.Valid || !feed.LastFetchedAt.Time.Equal(s.clock.Now()) {
+		t.Errorf("last fetched at %+v, want the clock's %v", feed.LastFetchedAt, s.clock.Now())
+	}
+
+	clock := s.clock.(*testClock)
+	clock.now = clock.now.Add(time.Minute)
+	feed, _, _ = nextFeed(context.Background(), s)
+	if feed.ID != second.ID {
+		t.Errorf("nextFeed = %v, want the feed that was never fetched", feed.Name)
+	}
+
+	// once every feed has been fetched they take turns, oldest fetch first
+	for _, want := range []database.Feed{first, second, first} {
+		clock.now = clock.now.Add(time.Minute)
+		feed, _, _ = nextFeed(context.Background(), s)
+		if feed.ID != want.ID {
+			t.Errorf("nextFeed = %v after a full cycle, want %v", feed.Name, want.Name)
+		}
+	}
+}
+
+func TestRefreshFeeds(t *testing.T) {
+	srv := feedServer(t)
+	s, store := newTestState(t, srv)
+	store.addFeed("main", srv.URL+"/feeds/rss2.xml")
+	store.addFeed("cafe", srv.URL+"/feeds/latin1.xml")
+	store.addFeed("broken", srv.URL+"/status/500")
+
+	urls := []string{
+		srv.URL + "/feeds/rss2.xml",
+		srv.URL + "/unknown.xml",
+		srv.URL + "/feeds/latin1.xml",
+		srv.URL + "/status/500",
+	}
+	rows := refreshFeeds(context.Background(), s, urls, 2, func(ctx context.Context, feed database.Feed) (int, error) {
+		return scrapeFeed(ctx, s, feed)
+	})
+
+	want := []refreshRow{
+		{Feed: "main", New: 3},
+		{Feed: RedactURL(srv.URL + "/unknown.xml"), Error: "unable to find feed: not found"},
+		{Feed: "cafe", New: 1},
+		{Feed: "broken", Error: "unable to list feed broken: unexpected response status: 500 Internal Server Error"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %v rows, want %v", len(rows), len(want))
+	}
+	for i, w := range want {
+		got := rows[i]
+		if got.Feed != w.Feed || got.New != w.New || got.Error != w.Error || got.Url != urls[i] {
+			t.Errorf("row %v = %+v, want %+v for %v", i, got, w, urls[i])
+		}
+	}
+}
+
+func TestRefreshURLsOnce(t *testing.T) {
+	s := &State{point: &Config{}}
+	cmd := Command{Name: "refresh", Arguments: []string{"https://a.example/feed", "https://b.example/feed", "https://a.example/feed"}}
+	urls, err := refreshURLs(s, cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(urls, []string{"https://a.example/feed", "https://b.example/feed"}) {
+		t.Errorf("refreshURLs() = %q, want each url once in the order given", urls)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Queries the scraper needs, the Postgres or SQLite queries in gator and an
+// in-memory store in the tests
+type feedStore interface {
+	GetNextFeedToFetch(ctx context.Context) (database.Feed, error)
+	GetFeed(ctx context.Context, url string) (database.Feed, error)
+	MarkFeedFetched(ctx context.Context, id uuid.UUID) (database.Feed, error)
+	CountFeeds(ctx context.Context) (int64, error)
+	CountOverdueFeeds(ctx context.Context, cutoff sql.NullTime) (int64, error)
+	CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) error
+	GetFeedCredentialByUrl(ctx context.Context, url string) (database.FeedCredential, error)
+	GetFilterRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.FilterRule, error)
+	// Returns sql.ErrNoRows when the feed already has the post
+	CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error)
+	CreatePostCategory(ctx context.Context, arg database.CreatePostCategoryParams) error
+	CreatePostEnclosure(ctx context.Context, arg database.CreatePostEnclosureParams) error
+	SetPostContent(ctx context.Context, arg database.SetPostContentParams) error
+	SetPostHidden(ctx context.Context, arg database.SetPostHiddenParams) error
+	SetPostStarred(ctx context.Context, arg database.SetPostStarredParams) error
+	MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error
+}
+
+// Sends requests for feeds, articles and enclosures, an *http.Client in gator
+type fetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ScooballyD/gator/internal/apperr"
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// A followed feed and the folders it's filed under
+type taggedFollow struct {
+	ID    uuid.UUID
+	Name  string
+	Url   string
+	Notes string
+	Tags  []string
+}
+
+// Cleans up a tag, "/" separates nested folders
+func normalizeTag(raw string) (string, error) {
+	var parts []string
+	for _, p := range strings.Split(raw, "/") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return "", apperr.New(apperr.Validation, "invalid tag %q", raw)
+	}
+	return strings.Join(parts, "/"), nil
+}
+
+// Loads the user's follows together with their tags
+func (s State) taggedFollows(ctx context.Context, user database.User) ([]taggedFollow, error) {
+	follows, err := s.dbq.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		return nil, apperr.WrapDB(err, "unable to retrieve followed feeds")
+	}
+	tags, err := s.dbq.GetFollowTagsForUser(ctx, user.ID)
+	if err != nil {
+		return nil, apperr.WrapDB(err, "unable to retrieve tags")
+	}
+
+	byFollow := map[uuid.UUID][]string{}
+	for _, t := range tags {
+		byFollow[t.FeedFollowID] = append(byFollow[t.FeedFollowID], t.Name)
+	}
+
+	out := make([]taggedFollow, 0, len(follows))
+	for _, f := range follows {
+		out = append(out, taggedFollow{
+			ID:    f.ID,
+			Name:  f.FeedName,
+			Url:   f.FeedUrl,
+			Notes: f.Notes,
+			Tags:  byFollow[f.ID],
+		})
+	}
+	return out, nil
+}
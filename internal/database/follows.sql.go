@@ -22,11 +22,11 @@ WITH inserted_feed_follow AS (
         $4,
         $5
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, display_name, notes
 )
 
 SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.display_name, inserted_feed_follow.notes,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -45,13 +45,15 @@ type CreateFeedFollowParams struct {
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	DisplayName string
+	Notes       string
+	FeedName    string
+	UserName    string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -69,15 +71,49 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.DisplayName,
+		&i.Notes,
 		&i.FeedName,
 		&i.UserName,
 	)
 	return i, err
 }
 
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.display_name, feed_follows.notes FROM feed_follows
+INNER JOIN feeds
+ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+AND feeds.url = $2
+`
+
+type GetFeedFollowParams struct {
+	UserID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.Url)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.DisplayName,
+		&i.Notes,
+	)
+	return i, err
+}
+
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
-    feeds.name AS feed_name,
+    feed_follows.id,
+    feed_follows.feed_id,
+    COALESCE(NULLIF(feed_follows.display_name, ''), feeds.name) AS feed_name,
+    feeds.url AS feed_url,
+    feed_follows.notes,
     users.name AS user_name
 FROM feed_follows
 INNER JOIN feeds
@@ -85,10 +121,15 @@ ON feed_follows.feed_id = feeds.id
 INNER JOIN users
 ON feed_follows.user_id = users.id
 WHERE feed_follows.user_id = $1
+ORDER BY feed_name
 `
 
 type GetFeedFollowsForUserRow struct {
+	ID       uuid.UUID
+	FeedID   uuid.UUID
 	FeedName string
+	FeedUrl  string
+	Notes    string
 	UserName string
 }
 
@@ -101,7 +142,14 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.FeedName, &i.UserName); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Notes,
+			&i.UserName,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -115,6 +163,40 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	return items, nil
 }
 
+const renameFeedFollow = `-- name: RenameFeedFollow :exec
+UPDATE feed_follows
+SET display_name = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type RenameFeedFollowParams struct {
+	ID          uuid.UUID
+	DisplayName string
+}
+
+func (q *Queries) RenameFeedFollow(ctx context.Context, arg RenameFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, renameFeedFollow, arg.ID, arg.DisplayName)
+	return err
+}
+
+const setFeedFollowNotes = `-- name: SetFeedFollowNotes :exec
+UPDATE feed_follows
+SET notes = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type SetFeedFollowNotesParams struct {
+	ID    uuid.UUID
+	Notes string
+}
+
+func (q *Queries) SetFeedFollowNotes(ctx context.Context, arg SetFeedFollowNotesParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFollowNotes, arg.ID, arg.Notes)
+	return err
+}
+
 const unfollow = `-- name: Unfollow :one
 DELETE FROM feed_follows
 WHERE feed_follows.user_id = $1
@@ -123,7 +205,7 @@ AND feed_id IN (
     FROM feeds
     WHERE feeds.url = $2 
 )
-RETURNING id, created_at, updated_at, user_id, feed_id
+RETURNING id, created_at, updated_at, user_id, feed_id, display_name, notes
 `
 
 type UnfollowParams struct {
@@ -140,6 +222,8 @@ func (q *Queries) Unfollow(ctx context.Context, arg UnfollowParams) (FeedFollow,
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.DisplayName,
+		&i.Notes,
 	)
 	return i, err
 }
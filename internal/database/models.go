@@ -11,33 +11,110 @@ import (
 	"github.com/google/uuid"
 )
 
+type EnclosureDownload struct {
+	UserID       uuid.UUID
+	EnclosureID  uuid.UUID
+	DownloadedAt time.Time
+	Path         string
+	Bytes        int64
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Name              string
+	Url               string
+	UserID            uuid.UUID
+	LastFetchedAt     sql.NullTime
+	FetchFullContent  bool
+	RetentionDays     sql.NullInt32
+	RetentionMaxPosts sql.NullInt32
 }
 
-type FeedFollow struct {
-	ID        uuid.UUID
+type FeedCredential struct {
+	FeedID    uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
+	AuthType  string
+	AuthName  string
+	Secret    []byte
 }
 
-type Post struct {
+type FeedFetch struct {
+	ID              uuid.UUID
+	FeedID          uuid.UUID
+	FetchedAt       time.Time
+	StatusCode      int32
+	ContentEncoding string
+	WireBytes       int64
+	DecodedBytes    int64
+}
+
+type FeedFollow struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
+	UserID      uuid.UUID
 	FeedID      uuid.UUID
+	DisplayName string
+	Notes       string
+}
+
+type FilterRule struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UserID        uuid.UUID
+	FeedID        uuid.NullUUID
+	TitlePattern  string
+	AuthorPattern string
+	Keyword       string
+	Action        string
+}
+
+type FollowTag struct {
+	FeedFollowID uuid.UUID
+	Name         string
+}
+
+type Post struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     string
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Guid            string
+	GuidIsPermalink bool
+	Author          string
+	ContentEncoded  string
+	Comments        string
+	IdentityKey     string
+	Content         string
+	LinkKey         string
+}
+
+type PostCategory struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+type PostEnclosure struct {
+	ID     uuid.UUID
+	PostID uuid.UUID
+	Url    string
+	Type   string
+	Length int64
+}
+
+type PostState struct {
+	UserID  uuid.UUID
+	PostID  uuid.UUID
+	Starred bool
+	ReadAt  sql.NullTime
+	Hidden  bool
 }
 
 type User struct {
@@ -45,4 +122,5 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
+	Role      string
 }
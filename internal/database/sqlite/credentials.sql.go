@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const deleteFeedCredential = `-- name: DeleteFeedCredential :execrows
+DELETE FROM feed_credentials
+WHERE feed_id = ?1
+`
+
+func (q *Queries) DeleteFeedCredential(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedCredential, feedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedCredentialByUrl = `-- name: GetFeedCredentialByUrl :one
+SELECT feed_credentials.feed_id, feed_credentials.created_at, feed_credentials.updated_at, feed_credentials.auth_type, feed_credentials.auth_name, feed_credentials.secret FROM feed_credentials
+INNER JOIN feeds
+ON feed_credentials.feed_id = feeds.id
+WHERE feeds.url = ?1
+`
+
+func (q *Queries) GetFeedCredentialByUrl(ctx context.Context, url string) (database.FeedCredential, error) {
+	row := q.db.QueryRowContext(ctx, getFeedCredentialByUrl, url)
+	var i database.FeedCredential
+	err := row.Scan(
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AuthType,
+		&i.AuthName,
+		&i.Secret,
+	)
+	return i, err
+}
+
+const setFeedCredential = `-- name: SetFeedCredential :one
+INSERT INTO feed_credentials (feed_id, created_at, updated_at, auth_type, auth_name, secret)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    auth_type = EXCLUDED.auth_type,
+    auth_name = EXCLUDED.auth_name,
+    secret = EXCLUDED.secret
+RETURNING feed_id, created_at, updated_at, auth_type, auth_name, secret
+`
+
+func (q *Queries) SetFeedCredential(ctx context.Context, arg database.SetFeedCredentialParams) (database.FeedCredential, error) {
+	row := q.db.QueryRowContext(ctx, setFeedCredential,
+		arg.FeedID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.AuthType,
+		arg.AuthName,
+		arg.Secret,
+	)
+	var i database.FeedCredential
+	err := row.Scan(
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AuthType,
+		&i.AuthName,
+		&i.Secret,
+	)
+	return i, err
+}
@@ -0,0 +1,80 @@
+// Package sqlite runs gator's queries against SQLite. The queries mirror the
+// Postgres ones in sql/queries and are kept by hand in the *.sql.go files,
+// sqlc can't generate methods that return package database's types. Each
+// method has the same signature as its counterpart in package database so
+// both satisfy database.Querier, change a query here when sql/queries changes
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/database"
+)
+
+// SQLite has no timestamp type, times are stored as UTC text in the layout
+// CURRENT_TIMESTAMP uses so they compare and sort in order
+const timeFormat = "2006-01-02 15:04:05.999999999"
+
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+func New(db DBTX) *Queries {
+	return &Queries{db: utcTimes{db}}
+}
+
+type Queries struct {
+	db DBTX
+}
+
+var _ database.Querier = (*Queries)(nil)
+
+func (q *Queries) WithTx(tx *sql.Tx) *Queries {
+	return &Queries{
+		db: utcTimes{tx},
+	}
+}
+
+// Stores time arguments in timeFormat, the driver would keep their time zone
+type utcTimes struct {
+	db DBTX
+}
+
+func (u utcTimes) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return u.db.ExecContext(ctx, query, timeArgs(args)...)
+}
+
+func (u utcTimes) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return u.db.PrepareContext(ctx, query)
+}
+
+func (u utcTimes) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return u.db.QueryContext(ctx, query, timeArgs(args)...)
+}
+
+func (u utcTimes) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return u.db.QueryRowContext(ctx, query, timeArgs(args)...)
+}
+
+func timeArgs(args []interface{}) []interface{} {
+	for i, arg := range args {
+		// sql.NullTime and the like
+		if v, ok := arg.(driver.Valuer); ok {
+			value, err := v.Value()
+			if err != nil {
+				continue
+			}
+			arg = value
+		}
+		if t, ok := arg.(time.Time); ok {
+			args[i] = t.UTC().Format(timeFormat)
+		}
+	}
+	return args
+}
@@ -0,0 +1,189 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const getEnclosuresForPost = `-- name: GetEnclosuresForPost :many
+SELECT
+    post_enclosures.id, post_enclosures.post_id, post_enclosures.url, post_enclosures.type, post_enclosures.length,
+    posts.title AS post_title,
+    posts.published_at,
+    feeds.name AS feed_name
+FROM post_enclosures
+INNER JOIN posts
+ON post_enclosures.post_id = posts.id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+WHERE post_enclosures.post_id = ?1
+`
+
+func (q *Queries) GetEnclosuresForPost(ctx context.Context, postID uuid.UUID) ([]database.GetEnclosuresForPostRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEnclosuresForPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetEnclosuresForPostRow
+	for rows.Next() {
+		var i database.GetEnclosuresForPostRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.Url,
+			&i.Type,
+			&i.Length,
+			&i.PostTitle,
+			&i.PublishedAt,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEnclosuresForUser = `-- name: GetEnclosuresForUser :many
+SELECT
+    post_enclosures.id, post_enclosures.post_id, post_enclosures.url, post_enclosures.type, post_enclosures.length,
+    posts.title AS post_title,
+    posts.published_at,
+    feeds.name AS feed_name,
+    enclosure_downloads.path AS downloaded_path
+FROM post_enclosures
+INNER JOIN posts
+ON post_enclosures.post_id = posts.id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+INNER JOIN feed_follows
+ON feed_follows.feed_id = feeds.id
+LEFT JOIN enclosure_downloads
+ON enclosure_downloads.enclosure_id = post_enclosures.id
+AND enclosure_downloads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = ?1
+ORDER BY posts.published_at DESC
+LIMIT ?2
+`
+
+func (q *Queries) GetEnclosuresForUser(ctx context.Context, arg database.GetEnclosuresForUserParams) ([]database.GetEnclosuresForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getEnclosuresForUser, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetEnclosuresForUserRow
+	for rows.Next() {
+		var i database.GetEnclosuresForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.Url,
+			&i.Type,
+			&i.Length,
+			&i.PostTitle,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.DownloadedPath,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPendingEnclosuresForUser = `-- name: GetPendingEnclosuresForUser :many
+SELECT
+    post_enclosures.id, post_enclosures.post_id, post_enclosures.url, post_enclosures.type, post_enclosures.length,
+    posts.title AS post_title,
+    posts.published_at,
+    feeds.name AS feed_name
+FROM post_enclosures
+INNER JOIN posts
+ON post_enclosures.post_id = posts.id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+INNER JOIN feed_follows
+ON feed_follows.feed_id = feeds.id
+LEFT JOIN enclosure_downloads
+ON enclosure_downloads.enclosure_id = post_enclosures.id
+AND enclosure_downloads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = ?1
+AND enclosure_downloads.enclosure_id IS NULL
+AND posts.created_at >= ?2
+ORDER BY posts.published_at
+`
+
+func (q *Queries) GetPendingEnclosuresForUser(ctx context.Context, arg database.GetPendingEnclosuresForUserParams) ([]database.GetPendingEnclosuresForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPendingEnclosuresForUser, arg.UserID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetPendingEnclosuresForUserRow
+	for rows.Next() {
+		var i database.GetPendingEnclosuresForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.Url,
+			&i.Type,
+			&i.Length,
+			&i.PostTitle,
+			&i.PublishedAt,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEnclosureDownloaded = `-- name: MarkEnclosureDownloaded :exec
+INSERT INTO enclosure_downloads (user_id, enclosure_id, downloaded_at, path, bytes)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5
+)
+ON CONFLICT (user_id, enclosure_id) DO UPDATE
+SET downloaded_at = EXCLUDED.downloaded_at,
+    path = EXCLUDED.path,
+    bytes = EXCLUDED.bytes
+`
+
+func (q *Queries) MarkEnclosureDownloaded(ctx context.Context, arg database.MarkEnclosureDownloadedParams) error {
+	_, err := q.db.ExecContext(ctx, markEnclosureDownloaded,
+		arg.UserID,
+		arg.EnclosureID,
+		arg.DownloadedAt,
+		arg.Path,
+		arg.Bytes,
+	)
+	return err
+}
@@ -0,0 +1,385 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const countFeeds = `-- name: CountFeeds :one
+SELECT COUNT(*) FROM feeds
+`
+
+func (q *Queries) CountFeeds(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeeds)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countOverdueFeeds = `-- name: CountOverdueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE last_fetched_at IS NULL OR last_fetched_at < ?1
+`
+
+func (q *Queries) CountOverdueFeeds(ctx context.Context, cutoff sql.NullTime) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOverdueFeeds, cutoff)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createFeed = `-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retention_days, retention_max_posts
+`
+
+func (q *Queries) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, createFeed,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+	)
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetentionDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = ?1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const deleteFeedIfOrphaned = `-- name: DeleteFeedIfOrphaned :execrows
+DELETE FROM feeds
+WHERE id = ?1
+AND NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+)
+`
+
+func (q *Queries) DeleteFeedIfOrphaned(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedIfOrphaned, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedFeeds = `-- name: DeleteOrphanedFeeds :many
+DELETE FROM feeds
+WHERE NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+)
+RETURNING name
+`
+
+func (q *Queries) DeleteOrphanedFeeds(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, deleteOrphanedFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeed = `-- name: GetFeed :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retention_days, retention_max_posts FROM feeds
+WHERE url = ?1
+`
+
+func (q *Queries) GetFeed(ctx context.Context, url string) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeed, url)
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetentionDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const getFeedUsage = `-- name: GetFeedUsage :one
+SELECT
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = ?1) AS posts,
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = ?1) AS followers
+`
+
+func (q *Queries) GetFeedUsage(ctx context.Context, feedID uuid.UUID) (database.GetFeedUsageRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedUsage, feedID)
+	var i database.GetFeedUsageRow
+	err := row.Scan(&i.Posts, &i.Followers)
+	return i, err
+}
+
+const getFeeds = `-- name: GetFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retention_days, retention_max_posts FROM feeds
+`
+
+func (q *Queries) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Feed
+	for rows.Next() {
+		var i database.Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchFullContent,
+			&i.RetentionDays,
+			&i.RetentionMaxPosts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retention_days, retention_max_posts FROM feeds
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT 1
+`
+
+func (q *Queries) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetentionDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const getOrphanedFeeds = `-- name: GetOrphanedFeeds :many
+SELECT name FROM feeds
+WHERE NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+)
+`
+
+func (q *Queries) GetOrphanedFeeds(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getOrphanedFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSavedPostCounts = `-- name: GetSavedPostCounts :one
+SELECT
+    (SELECT COUNT(*) FROM post_states
+        INNER JOIN posts ON post_states.post_id = posts.id
+        WHERE posts.feed_id = ?1
+        AND post_states.user_id = ?2
+        AND post_states.starred) AS starred,
+    (SELECT COUNT(*) FROM enclosure_downloads
+        INNER JOIN post_enclosures ON enclosure_downloads.enclosure_id = post_enclosures.id
+        INNER JOIN posts ON post_enclosures.post_id = posts.id
+        WHERE posts.feed_id = ?1
+        AND enclosure_downloads.user_id = ?2) AS downloads
+`
+
+func (q *Queries) GetSavedPostCounts(ctx context.Context, arg database.GetSavedPostCountsParams) (database.GetSavedPostCountsRow, error) {
+	row := q.db.QueryRowContext(ctx, getSavedPostCounts, arg.FeedID, arg.UserID)
+	var i database.GetSavedPostCountsRow
+	err := row.Scan(&i.Starred, &i.Downloads)
+	return i, err
+}
+
+const markFeedFetched = `-- name: MarkFeedFetched :one
+UPDATE feeds
+SET last_fetched_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retention_days, retention_max_posts
+`
+
+func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, markFeedFetched, id)
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetentionDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const setFeedFullContent = `-- name: SetFeedFullContent :one
+UPDATE feeds
+SET fetch_full_content = ?2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retention_days, retention_max_posts
+`
+
+func (q *Queries) SetFeedFullContent(ctx context.Context, arg database.SetFeedFullContentParams) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedFullContent, arg.ID, arg.FetchFullContent)
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetentionDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const setFeedOwner = `-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = ?2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?1
+`
+
+func (q *Queries) SetFeedOwner(ctx context.Context, arg database.SetFeedOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedOwner, arg.ID, arg.UserID)
+	return err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :one
+UPDATE feeds
+SET retention_days = ?2,
+    retention_max_posts = ?3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_content, retention_days, retention_max_posts
+`
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) (database.Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedRetention, arg.ID, arg.RetentionDays, arg.RetentionMaxPosts)
+	var i database.Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullContent,
+		&i.RetentionDays,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const transferFeeds = `-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = ?1,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = ?2
+`
+
+func (q *Queries) TransferFeeds(ctx context.Context, arg database.TransferFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeeds, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/database"
+)
+
+const createFeedFetch = `-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (id, feed_id, fetched_at, status_code, content_encoding, wire_bytes, decoded_bytes)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7
+)
+`
+
+func (q *Queries) CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) error {
+	_, err := q.db.ExecContext(ctx, createFeedFetch,
+		arg.ID,
+		arg.FeedID,
+		arg.FetchedAt,
+		arg.StatusCode,
+		arg.ContentEncoding,
+		arg.WireBytes,
+		arg.DecodedBytes,
+	)
+	return err
+}
+
+const getFeedBandwidthStats = `-- name: GetFeedBandwidthStats :many
+SELECT
+    feeds.name,
+    feeds.url,
+    COUNT(feed_fetches.id) AS fetches,
+    CAST(COALESCE(SUM(feed_fetches.wire_bytes), 0) AS INTEGER) AS wire_bytes,
+    CAST(COALESCE(SUM(feed_fetches.decoded_bytes), 0) AS INTEGER) AS decoded_bytes
+FROM feeds
+LEFT JOIN feed_fetches
+ON feed_fetches.feed_id = feeds.id
+AND feed_fetches.fetched_at >= ?1
+GROUP BY feeds.id, feeds.name, feeds.url
+ORDER BY wire_bytes DESC, feeds.name
+`
+
+func (q *Queries) GetFeedBandwidthStats(ctx context.Context, fetchedAt time.Time) ([]database.GetFeedBandwidthStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedBandwidthStats, fetchedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetFeedBandwidthStatsRow
+	for rows.Next() {
+		var i database.GetFeedBandwidthStatsRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.Fetches,
+			&i.WireBytes,
+			&i.DecodedBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,140 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createFilterRule = `-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, created_at, user_id, feed_id, title_pattern, author_pattern, keyword, action)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8
+)
+RETURNING id, created_at, user_id, feed_id, title_pattern, author_pattern, keyword, action
+`
+
+func (q *Queries) CreateFilterRule(ctx context.Context, arg database.CreateFilterRuleParams) (database.FilterRule, error) {
+	row := q.db.QueryRowContext(ctx, createFilterRule,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.TitlePattern,
+		arg.AuthorPattern,
+		arg.Keyword,
+		arg.Action,
+	)
+	var i database.FilterRule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.TitlePattern,
+		&i.AuthorPattern,
+		&i.Keyword,
+		&i.Action,
+	)
+	return i, err
+}
+
+const deleteFilterRule = `-- name: DeleteFilterRule :exec
+DELETE FROM filter_rules
+WHERE id = ?1
+`
+
+func (q *Queries) DeleteFilterRule(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFilterRule, id)
+	return err
+}
+
+const getFilterRulesForFeed = `-- name: GetFilterRulesForFeed :many
+SELECT filter_rules.id, filter_rules.created_at, filter_rules.user_id, filter_rules.feed_id, filter_rules.title_pattern, filter_rules.author_pattern, filter_rules.keyword, filter_rules.action FROM filter_rules
+INNER JOIN feed_follows
+ON filter_rules.user_id = feed_follows.user_id
+WHERE feed_follows.feed_id = ?1
+AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = ?1)
+ORDER BY filter_rules.created_at
+`
+
+func (q *Queries) GetFilterRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.FilterRule, error) {
+	rows, err := q.db.QueryContext(ctx, getFilterRulesForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.FilterRule
+	for rows.Next() {
+		var i database.FilterRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.TitlePattern,
+			&i.AuthorPattern,
+			&i.Keyword,
+			&i.Action,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFilterRulesForUser = `-- name: GetFilterRulesForUser :many
+SELECT filter_rules.id, filter_rules.created_at, filter_rules.user_id, filter_rules.feed_id, filter_rules.title_pattern, filter_rules.author_pattern, filter_rules.keyword, filter_rules.action, feeds.url AS feed_url FROM filter_rules
+LEFT JOIN feeds
+ON filter_rules.feed_id = feeds.id
+WHERE filter_rules.user_id = ?1
+ORDER BY filter_rules.created_at
+`
+
+func (q *Queries) GetFilterRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFilterRulesForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFilterRulesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetFilterRulesForUserRow
+	for rows.Next() {
+		var i database.GetFilterRulesForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.TitlePattern,
+			&i.AuthorPattern,
+			&i.Keyword,
+			&i.Action,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,166 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createFeedFollow = `-- name: CreateFeedFollow :one
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5
+)
+RETURNING
+    id, created_at, updated_at, user_id, feed_id, display_name, notes,
+    (SELECT name FROM feeds WHERE feeds.id = feed_follows.feed_id) AS feed_name,
+    (SELECT name FROM users WHERE users.id = feed_follows.user_id) AS user_name
+`
+
+func (q *Queries) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	row := q.db.QueryRowContext(ctx, createFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	var i database.CreateFeedFollowRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.DisplayName,
+		&i.Notes,
+		&i.FeedName,
+		&i.UserName,
+	)
+	return i, err
+}
+
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.display_name, feed_follows.notes FROM feed_follows
+INNER JOIN feeds
+ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = ?1
+AND feeds.url = ?2
+`
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg database.GetFeedFollowParams) (database.FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.Url)
+	var i database.FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.DisplayName,
+		&i.Notes,
+	)
+	return i, err
+}
+
+const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
+SELECT
+    feed_follows.id,
+    feed_follows.feed_id,
+    COALESCE(NULLIF(feed_follows.display_name, ''), feeds.name) AS feed_name,
+    feeds.url AS feed_url,
+    feed_follows.notes,
+    users.name AS user_name
+FROM feed_follows
+INNER JOIN feeds
+ON feed_follows.feed_id = feeds.id
+INNER JOIN users
+ON feed_follows.user_id = users.id
+WHERE feed_follows.user_id = ?1
+ORDER BY feed_name
+`
+
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetFeedFollowsForUserRow
+	for rows.Next() {
+		var i database.GetFeedFollowsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Notes,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameFeedFollow = `-- name: RenameFeedFollow :exec
+UPDATE feed_follows
+SET display_name = ?2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?1
+`
+
+func (q *Queries) RenameFeedFollow(ctx context.Context, arg database.RenameFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, renameFeedFollow, arg.ID, arg.DisplayName)
+	return err
+}
+
+const setFeedFollowNotes = `-- name: SetFeedFollowNotes :exec
+UPDATE feed_follows
+SET notes = ?2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?1
+`
+
+func (q *Queries) SetFeedFollowNotes(ctx context.Context, arg database.SetFeedFollowNotesParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFollowNotes, arg.ID, arg.Notes)
+	return err
+}
+
+const unfollow = `-- name: Unfollow :one
+DELETE FROM feed_follows
+WHERE feed_follows.user_id = ?1
+AND feed_id IN (
+    SELECT id
+    FROM feeds
+    WHERE feeds.url = ?2 
+)
+RETURNING id, created_at, updated_at, user_id, feed_id, display_name, notes
+`
+
+func (q *Queries) Unfollow(ctx context.Context, arg database.UnfollowParams) (database.FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, unfollow, arg.UserID, arg.Url)
+	var i database.FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.DisplayName,
+		&i.Notes,
+	)
+	return i, err
+}
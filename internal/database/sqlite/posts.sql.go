@@ -0,0 +1,424 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const createPost = `-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id,
+    guid, guid_is_permalink, author, content_encoded, comments, identity_key, link_key)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    ?8,
+    ?9,
+    ?10,
+    ?11,
+    ?12,
+    ?13,
+    ?14,
+    ?15
+)
+ON CONFLICT (feed_id, identity_key) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid, guid_is_permalink, author, content_encoded, comments, identity_key, content, link_key
+`
+
+func (q *Queries) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	row := q.db.QueryRowContext(ctx, createPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+		arg.GuidIsPermalink,
+		arg.Author,
+		arg.ContentEncoded,
+		arg.Comments,
+		arg.IdentityKey,
+		arg.LinkKey,
+	)
+	var i database.Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.GuidIsPermalink,
+		&i.Author,
+		&i.ContentEncoded,
+		&i.Comments,
+		&i.IdentityKey,
+		&i.Content,
+		&i.LinkKey,
+	)
+	return i, err
+}
+
+const createPostCategory = `-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES (?1, ?2)
+ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) CreatePostCategory(ctx context.Context, arg database.CreatePostCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, createPostCategory, arg.PostID, arg.Name)
+	return err
+}
+
+const createPostEnclosure = `-- name: CreatePostEnclosure :exec
+INSERT INTO post_enclosures (id, post_id, url, type, length)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    ?5
+)
+`
+
+func (q *Queries) CreatePostEnclosure(ctx context.Context, arg database.CreatePostEnclosureParams) error {
+	_, err := q.db.ExecContext(ctx, createPostEnclosure,
+		arg.ID,
+		arg.PostID,
+		arg.Url,
+		arg.Type,
+		arg.Length,
+	)
+	return err
+}
+
+const getFeedsForLinks = `-- name: GetFeedsForLinks :many
+SELECT posts.link_key, posts.feed_id, COALESCE(NULLIF(feed_follows.display_name, ''), feeds.name) AS name FROM posts
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows
+ON feed_follows.feed_id = feeds.id
+AND feed_follows.user_id = ?1
+WHERE posts.link_key IN (SELECT value FROM json_each(?2))
+ORDER BY name
+`
+
+func (q *Queries) GetFeedsForLinks(ctx context.Context, arg database.GetFeedsForLinksParams) ([]database.GetFeedsForLinksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsForLinks, arg.UserID, arg.LinkKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetFeedsForLinksRow
+	for rows.Next() {
+		var i database.GetFeedsForLinksRow
+		if err := rows.Scan(&i.LinkKey, &i.FeedID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLinkKeyedPosts = `-- name: GetLinkKeyedPosts :many
+SELECT id, identity_key FROM posts
+WHERE identity_key LIKE 'link:%?%'
+`
+
+func (q *Queries) GetLinkKeyedPosts(ctx context.Context) ([]database.GetLinkKeyedPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLinkKeyedPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetLinkKeyedPostsRow
+	for rows.Next() {
+		var i database.GetLinkKeyedPostsRow
+		if err := rows.Scan(&i.ID, &i.IdentityKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostCategories = `-- name: GetPostCategories :many
+SELECT name FROM post_categories
+WHERE post_id = ?1
+ORDER BY name
+`
+
+func (q *Queries) GetPostCategories(ctx context.Context, postID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getPostCategories, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostEnclosures = `-- name: GetPostEnclosures :many
+SELECT id, post_id, url, type, length FROM post_enclosures
+WHERE post_id = ?1
+`
+
+func (q *Queries) GetPostEnclosures(ctx context.Context, postID uuid.UUID) ([]database.PostEnclosure, error) {
+	rows, err := q.db.QueryContext(ctx, getPostEnclosures, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.PostEnclosure
+	for rows.Next() {
+		var i database.PostEnclosure
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.Url,
+			&i.Type,
+			&i.Length,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByIDPrefix = `-- name: GetPostsByIDPrefix :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.guid_is_permalink, posts.author, posts.content_encoded, posts.comments, posts.identity_key, posts.content, posts.link_key FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = ?1
+AND substr(posts.id, 1, length(?2)) = ?2
+LIMIT 2
+`
+
+func (q *Queries) GetPostsByIDPrefix(ctx context.Context, arg database.GetPostsByIDPrefixParams) ([]database.Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByIDPrefix, arg.UserID, arg.IDPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Post
+	for rows.Next() {
+		var i database.Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.GuidIsPermalink,
+			&i.Author,
+			&i.ContentEncoded,
+			&i.Comments,
+			&i.IdentityKey,
+			&i.Content,
+			&i.LinkKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUser = `-- name: GetPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.guid_is_permalink, posts.author, posts.content_encoded, posts.comments, posts.identity_key, posts.content, posts.link_key,
+    COALESCE(post_states.starred, false) AS starred,
+    post_states.read_at,
+    COALESCE(post_states.hidden, false) AS hidden
+FROM posts
+LEFT JOIN post_states
+ON post_states.post_id = posts.id
+AND post_states.user_id = ?1
+WHERE posts.feed_id IN(
+    SELECT feed_id FROM feed_follows
+    WHERE user_id = ?1
+    AND (?3 = '' OR EXISTS (
+        SELECT 1 FROM follow_tags
+        WHERE follow_tags.feed_follow_id = feed_follows.id
+        AND (follow_tags.name = ?3
+            OR substr(follow_tags.name, 1, length(?3) + 1) = ?3 || '/')
+    ))
+)
+AND (?4 OR NOT COALESCE(post_states.hidden, false))
+ORDER BY posts.published_at DESC
+LIMIT ?2
+OFFSET ?5
+`
+
+func (q *Queries) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Limit,
+		arg.Tag,
+		arg.IncludeHidden,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetPostsForUserRow
+	for rows.Next() {
+		var i database.GetPostsForUserRow
+		if err := rows.Scan(
+			&i.Post.ID,
+			&i.Post.CreatedAt,
+			&i.Post.UpdatedAt,
+			&i.Post.Title,
+			&i.Post.Url,
+			&i.Post.Description,
+			&i.Post.PublishedAt,
+			&i.Post.FeedID,
+			&i.Post.Guid,
+			&i.Post.GuidIsPermalink,
+			&i.Post.Author,
+			&i.Post.ContentEncoded,
+			&i.Post.Comments,
+			&i.Post.IdentityKey,
+			&i.Post.Content,
+			&i.Post.LinkKey,
+			&i.Starred,
+			&i.ReadAt,
+			&i.Hidden,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsWithoutLinkKey = `-- name: GetPostsWithoutLinkKey :many
+SELECT id, url FROM posts
+WHERE link_key = ''
+AND url <> ''
+`
+
+func (q *Queries) GetPostsWithoutLinkKey(ctx context.Context) ([]database.GetPostsWithoutLinkKeyRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsWithoutLinkKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetPostsWithoutLinkKeyRow
+	for rows.Next() {
+		var i database.GetPostsWithoutLinkKeyRow
+		if err := rows.Scan(&i.ID, &i.Url); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content = ?2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?1
+`
+
+func (q *Queries) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.ID, arg.Content)
+	return err
+}
+
+const setPostIdentityKey = `-- name: SetPostIdentityKey :execrows
+UPDATE posts
+SET identity_key = ?2
+WHERE id = ?1
+AND NOT EXISTS (
+    SELECT 1 FROM posts other
+    WHERE other.feed_id = posts.feed_id
+    AND other.identity_key = ?2
+)
+`
+
+func (q *Queries) SetPostIdentityKey(ctx context.Context, arg database.SetPostIdentityKeyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setPostIdentityKey, arg.ID, arg.IdentityKey)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setPostLinkKey = `-- name: SetPostLinkKey :exec
+UPDATE posts
+SET link_key = ?2
+WHERE id = ?1
+`
+
+func (q *Queries) SetPostLinkKey(ctx context.Context, arg database.SetPostLinkKeyParams) error {
+	_, err := q.db.ExecContext(ctx, setPostLinkKey, arg.ID, arg.LinkKey)
+	return err
+}
@@ -0,0 +1,521 @@
+//go:build cgo
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/ScooballyD/gator/internal/migrate"
+	"github.com/ScooballyD/gator/sql/sqlite/schema"
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// In-memory database migrated to the latest version
+func newTestDB(t *testing.T) (*sql.DB, *Queries) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: gets a database of its own
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrate.New(db, migrate.SQLite, schema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, New(db)
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	db, _ := newTestDB(t)
+	m, err := migrate.New(db, migrate.SQLite, schema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := m.Current(ctx)
+	if err != nil || current != m.Latest() {
+		t.Fatalf("Current() = %v, %v, want %v", current, err, m.Latest())
+	}
+	done, err := m.To(ctx, 0)
+	if err != nil || len(done) != 2 {
+		t.Fatalf("To(0) = %v, %v", done, err)
+	}
+	current, _ = m.Current(ctx)
+	if current != 0 {
+		t.Fatalf("after To(0) the database is at %v", current)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil || statuses[0].Applied {
+		t.Fatalf("Status() = %+v, %v, want nothing applied", statuses, err)
+	}
+	_, err = m.Up(ctx)
+	if err != nil {
+		t.Fatalf("migrating up again: %v", err)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	ctx := context.Background()
+	_, q := newTestDB(t)
+	// not UTC, times are stored in UTC whatever zone they come in
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+
+	alice, err := q.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := q.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Role != "admin" || bob.Role != "member" {
+		t.Errorf("roles = %v, %v, want the first user to be the admin", alice.Role, bob.Role)
+	}
+	_, err = q.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "bob"})
+	if err == nil {
+		t.Error("a second bob was created")
+	}
+
+	feed, err := q.CreateFeed(ctx, database.CreateFeedParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "Blog", Url: "https://example.com/feed", UserID: alice.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow, err := q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: bob.ID, FeedID: feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if follow.FeedName != "Blog" || follow.UserName != "bob" {
+		t.Errorf("CreateFeedFollow() = %+v, want the feed and user names", follow)
+	}
+	err = q.AddFollowTag(ctx, database.AddFollowTagParams{FeedFollowID: follow.ID, Name: "tech/go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := database.CreatePostParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Title: "Hello", Url: "https://example.com/hello",
+		PublishedAt: now, FeedID: feed.ID, IdentityKey: "link:https://example.com/hello", LinkKey: "https://example.com/hello",
+	}
+	created, err := q.CreatePost(ctx, post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created.PublishedAt.Equal(now) {
+		t.Errorf("PublishedAt = %v, want %v", created.PublishedAt, now)
+	}
+	post.ID = uuid.New()
+	_, err = q.CreatePost(ctx, post)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("storing the same post again returned %v, want sql.ErrNoRows", err)
+	}
+
+	// the same article with tracking params, keyed by its raw link as the identity migration did
+	other, err := q.CreateFeed(ctx, database.CreateFeedParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "Aggregator", Url: "https://example.org/feed", UserID: alice.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracked := post
+	tracked.ID, tracked.FeedID = uuid.New(), other.ID
+	tracked.Url = "https://example.com/hello?utm_source=feed"
+	tracked.IdentityKey = "link:" + tracked.Url
+	_, err = q.CreatePost(ctx, tracked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alsoIn, err := q.GetFeedsForLinks(ctx, database.GetFeedsForLinksParams{
+		UserID: bob.ID, LinkKeys: `["https://example.com/hello", "https://example.com/missing"]`,
+	})
+	if err != nil || len(alsoIn) != 2 || alsoIn[0].Name != "Aggregator" || alsoIn[1].FeedID != feed.ID {
+		t.Errorf("GetFeedsForLinks() = %+v, %v, want both copies", alsoIn, err)
+	}
+	keyed, err := q.GetLinkKeyedPosts(ctx)
+	if err != nil || len(keyed) != 1 || keyed[0].ID != tracked.ID {
+		t.Errorf("GetLinkKeyedPosts() = %+v, %v, want only the link with a query", keyed, err)
+	}
+	n, err := q.SetPostIdentityKey(ctx, database.SetPostIdentityKeyParams{ID: tracked.ID, IdentityKey: post.IdentityKey})
+	if err != nil || n != 1 {
+		t.Errorf("SetPostIdentityKey() = %v, %v, want 1", n, err)
+	}
+	// another post of the feed already has the key
+	hashed := tracked
+	hashed.ID, hashed.IdentityKey = uuid.New(), "hash:1234"
+	_, err = q.CreatePost(ctx, hashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = q.SetPostIdentityKey(ctx, database.SetPostIdentityKeyParams{ID: hashed.ID, IdentityKey: post.IdentityKey})
+	if err != nil || n != 0 {
+		t.Errorf("SetPostIdentityKey() = %v, %v, want 0 for a key the feed already has", n, err)
+	}
+
+	for _, tc := range []struct {
+		tag  string
+		want int
+	}{{"", 1}, {"tech", 1}, {"tech/go", 1}, {"te", 0}, {"music", 0}} {
+		posts, err := q.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: bob.ID, Limit: 10, Tag: tc.tag})
+		if err != nil || len(posts) != tc.want {
+			t.Errorf("GetPostsForUser(tag %q) = %v posts, %v, want %v", tc.tag, len(posts), err, tc.want)
+		}
+	}
+	err = q.SetPostStarred(ctx, database.SetPostStarredParams{UserID: bob.ID, PostID: created.ID, Starred: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts, err := q.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: bob.ID, Limit: 10})
+	if err != nil || len(posts) != 1 || posts[0].Post.ID != created.ID || !posts[0].Starred || posts[0].ReadAt.Valid {
+		t.Errorf("GetPostsForUser() = %+v, %v, want the post with its state", posts, err)
+	}
+	err = q.SetPostHidden(ctx, database.SetPostHiddenParams{UserID: bob.ID, PostID: created.ID, Hidden: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts, _ = q.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: bob.ID, Limit: 10})
+	if len(posts) != 0 {
+		t.Errorf("hidden post was listed")
+	}
+
+	overdue, err := q.CountOverdueFeeds(ctx, sql.NullTime{Time: now, Valid: true})
+	if err != nil || overdue != 2 {
+		t.Errorf("CountOverdueFeeds() = %v, %v before the first fetch, want 2", overdue, err)
+	}
+	for _, id := range []uuid.UUID{feed.ID, other.ID} {
+		fetched, err := q.MarkFeedFetched(ctx, id)
+		if err != nil || !fetched.LastFetchedAt.Valid {
+			t.Fatalf("MarkFeedFetched() = %+v, %v", fetched, err)
+		}
+	}
+	overdue, _ = q.CountOverdueFeeds(ctx, sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true})
+	if overdue != 0 {
+		t.Errorf("CountOverdueFeeds() = %v right after a fetch, want 0", overdue)
+	}
+
+	// deleting a user takes their feeds, follows and post states with them
+	err = q.DeleteUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follows, err := q.GetFeedFollowsForUser(ctx, bob.ID)
+	if err != nil || len(follows) != 0 {
+		t.Errorf("GetFeedFollowsForUser() = %v, %v after the feed was deleted", follows, err)
+	}
+}
+
+// A user following a feed with posts published 3 days, 2 days and an hour before now
+type fixture struct {
+	user  database.User
+	feed  database.Feed
+	posts []database.Post
+}
+
+func newFixture(t *testing.T, q *Queries, now time.Time) fixture {
+	t.Helper()
+	ctx := context.Background()
+	user, err := q.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := q.CreateFeed(ctx, database.CreateFeedParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "Podcast", Url: "https://example.com/podcast", UserID: user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, UserID: user.ID, FeedID: feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := fixture{user: user, feed: feed}
+	for i, age := range []time.Duration{72 * time.Hour, 48 * time.Hour, time.Hour} {
+		url := fmt.Sprintf("https://example.com/ep/%v", i)
+		post, err := q.CreatePost(ctx, database.CreatePostParams{
+			ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Title: fmt.Sprintf("Episode %v", i), Url: url,
+			PublishedAt: now.Add(-age), FeedID: feed.ID, IdentityKey: "link:" + url,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.posts = append(f.posts, post)
+	}
+	return f
+}
+
+func postIDs(posts []database.GetPrunablePostsForFeedRow) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestPruneQueries(t *testing.T) {
+	ctx := context.Background()
+	_, q := newTestDB(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := newFixture(t, q, now)
+	oldest, starred, newest := f.posts[0], f.posts[1], f.posts[2]
+
+	for _, p := range []database.Post{oldest, starred} {
+		err := q.MarkPostRead(ctx, database.MarkPostReadParams{UserID: f.user.ID, PostID: p.ID, ReadAt: sql.NullTime{Time: now, Valid: true}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := q.SetPostStarred(ctx, database.SetPostStarredParams{UserID: f.user.ID, PostID: starred.ID, Starred: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		params database.GetPrunablePostsForFeedParams
+		want   []uuid.UUID
+	}{
+		{
+			name:   "older than a day",
+			params: database.GetPrunablePostsForFeedParams{Cutoff: now.Add(-24 * time.Hour), KeepNewest: 10, KeepUnread: true},
+			want:   []uuid.UUID{oldest.ID},
+		},
+		{
+			name:   "keep none, unread kept",
+			params: database.GetPrunablePostsForFeedParams{Cutoff: now.Add(-240 * time.Hour), KeepNewest: 0, KeepUnread: true},
+			want:   []uuid.UUID{oldest.ID},
+		},
+		{
+			name:   "keep none, unread too",
+			params: database.GetPrunablePostsForFeedParams{Cutoff: now.Add(-240 * time.Hour), KeepNewest: 0, KeepUnread: false},
+			want:   []uuid.UUID{oldest.ID, newest.ID},
+		},
+		{
+			name:   "keep the newest 2",
+			params: database.GetPrunablePostsForFeedParams{Cutoff: now.Add(-240 * time.Hour), KeepNewest: 2, KeepUnread: false},
+			want:   []uuid.UUID{oldest.ID},
+		},
+	} {
+		tc.params.FeedID = f.feed.ID
+		posts, err := q.GetPrunablePostsForFeed(ctx, tc.params)
+		if err != nil || !slices.Equal(postIDs(posts), tc.want) {
+			t.Errorf("%v: GetPrunablePostsForFeed() = %v, %v, want %v", tc.name, postIDs(posts), err, tc.want)
+		}
+	}
+}
+
+func TestFilterQueries(t *testing.T) {
+	ctx := context.Background()
+	_, q := newTestDB(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := newFixture(t, q, now)
+	other, err := q.CreateFeed(ctx, database.CreateFeedParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "Other", Url: "https://example.org/feed", UserID: f.user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rules []database.FilterRule
+	for i, feedID := range []uuid.NullUUID{{}, {UUID: f.feed.ID, Valid: true}, {UUID: other.ID, Valid: true}} {
+		rule, err := q.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+			ID: uuid.New(), CreatedAt: now.Add(time.Duration(i) * time.Minute), UserID: f.user.ID, FeedID: feedID,
+			Keyword: "ad", Action: "hide",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+
+	mine, err := q.GetFilterRulesForUser(ctx, f.user.ID)
+	if err != nil || len(mine) != 3 {
+		t.Fatalf("GetFilterRulesForUser() = %v rules, %v, want 3", len(mine), err)
+	}
+	if mine[0].FeedUrl.Valid || mine[1].FeedUrl.String != f.feed.Url {
+		t.Errorf("feed urls = %+v, %+v, want none for the rule on every feed", mine[0].FeedUrl, mine[1].FeedUrl)
+	}
+
+	// other isn't followed, so its rule doesn't apply anywhere yet
+	forFeed, err := q.GetFilterRulesForFeed(ctx, f.feed.ID)
+	if err != nil || len(forFeed) != 2 || forFeed[0].ID != rules[0].ID || forFeed[1].ID != rules[1].ID {
+		t.Errorf("GetFilterRulesForFeed() = %+v, %v, want the rules for every feed and for this one", forFeed, err)
+	}
+
+	err = q.DeleteFilterRule(ctx, rules[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forFeed, _ = q.GetFilterRulesForFeed(ctx, f.feed.ID)
+	if len(forFeed) != 1 {
+		t.Errorf("GetFilterRulesForFeed() = %v rules after a delete, want 1", len(forFeed))
+	}
+}
+
+func TestDownloadQueries(t *testing.T) {
+	ctx := context.Background()
+	_, q := newTestDB(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := newFixture(t, q, now)
+
+	enclosureID := uuid.New()
+	err := q.CreatePostEnclosure(ctx, database.CreatePostEnclosureParams{
+		ID: enclosureID, PostID: f.posts[2].ID, Url: "https://example.com/ep/2.mp3", Type: "audio/mpeg", Length: 1000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := q.GetEnclosuresForUser(ctx, database.GetEnclosuresForUserParams{UserID: f.user.ID, Limit: 10})
+	if err != nil || len(all) != 1 || all[0].DownloadedPath.Valid || all[0].FeedName != f.feed.Name {
+		t.Fatalf("GetEnclosuresForUser() = %+v, %v, want one episode not downloaded", all, err)
+	}
+	pending, err := q.GetPendingEnclosuresForUser(ctx, database.GetPendingEnclosuresForUserParams{UserID: f.user.ID, CreatedAt: now.Add(-time.Hour)})
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("GetPendingEnclosuresForUser() = %+v, %v, want the episode", pending, err)
+	}
+	pending, _ = q.GetPendingEnclosuresForUser(ctx, database.GetPendingEnclosuresForUserParams{UserID: f.user.ID, CreatedAt: now.Add(time.Hour)})
+	if len(pending) != 0 {
+		t.Errorf("GetPendingEnclosuresForUser() = %+v, want nothing stored after the cutoff", pending)
+	}
+
+	for _, path := range []string{"/dl/first.mp3", "/dl/second.mp3"} {
+		err = q.MarkEnclosureDownloaded(ctx, database.MarkEnclosureDownloadedParams{
+			UserID: f.user.ID, EnclosureID: enclosureID, DownloadedAt: now, Path: path, Bytes: 1000,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, _ = q.GetEnclosuresForUser(ctx, database.GetEnclosuresForUserParams{UserID: f.user.ID, Limit: 10})
+	if len(all) != 1 || all[0].DownloadedPath.String != "/dl/second.mp3" {
+		t.Errorf("GetEnclosuresForUser() = %+v, want the path of the latest download", all)
+	}
+	pending, _ = q.GetPendingEnclosuresForUser(ctx, database.GetPendingEnclosuresForUserParams{UserID: f.user.ID, CreatedAt: now.Add(-time.Hour)})
+	if len(pending) != 0 {
+		t.Errorf("GetPendingEnclosuresForUser() = %+v after the download, want nothing", pending)
+	}
+
+	err = q.SetPostStarred(ctx, database.SetPostStarredParams{UserID: f.user.ID, PostID: f.posts[0].ID, Starred: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := q.GetSavedPostCounts(ctx, database.GetSavedPostCountsParams{FeedID: f.feed.ID, UserID: f.user.ID})
+	if err != nil || saved.Starred != 1 || saved.Downloads != 1 {
+		t.Errorf("GetSavedPostCounts() = %+v, %v, want 1 star and 1 download", saved, err)
+	}
+}
+
+func TestBandwidthQueries(t *testing.T) {
+	ctx := context.Background()
+	_, q := newTestDB(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := newFixture(t, q, now)
+	quiet, err := q.CreateFeed(ctx, database.CreateFeedParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "Quiet", Url: "https://example.org/feed", UserID: f.user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, at := range []time.Time{now.Add(-48 * time.Hour), now.Add(-time.Hour), now} {
+		err = q.CreateFeedFetch(ctx, database.CreateFeedFetchParams{
+			ID: uuid.New(), FeedID: f.feed.ID, FetchedAt: at, StatusCode: 200,
+			ContentEncoding: "gzip", WireBytes: 100, DecodedBytes: 400,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := q.GetFeedBandwidthStats(ctx, now.Add(-24*time.Hour))
+	want := []database.GetFeedBandwidthStatsRow{
+		{Name: f.feed.Name, Url: f.feed.Url, Fetches: 2, WireBytes: 200, DecodedBytes: 800},
+		{Name: quiet.Name, Url: quiet.Url},
+	}
+	if err != nil || !slices.Equal(stats, want) {
+		t.Errorf("GetFeedBandwidthStats() = %+v, %v, want %+v", stats, err, want)
+	}
+}
+
+func TestNextFeedToFetch(t *testing.T) {
+	ctx := context.Background()
+	db, q := newTestDB(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := newFixture(t, q, now)
+	other, err := q.CreateFeed(ctx, database.CreateFeedParams{
+		ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "Other", Url: "https://example.org/feed", UserID: f.user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := q.CountFeeds(ctx)
+	if err != nil || n != 2 {
+		t.Errorf("CountFeeds() = %v, %v, want 2", n, err)
+	}
+
+	setFetched := func(id uuid.UUID, at time.Time) {
+		t.Helper()
+		_, err := db.Exec("UPDATE feeds SET last_fetched_at = ? WHERE id = ?", at.Format(timeFormat), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	setFetched(f.feed.ID, now)
+	next, err := q.GetNextFeedToFetch(ctx)
+	if err != nil || next.ID != other.ID {
+		t.Errorf("GetNextFeedToFetch() = %v, %v, want the feed never fetched", next.Name, err)
+	}
+	setFetched(other.ID, now.Add(time.Minute))
+	next, err = q.GetNextFeedToFetch(ctx)
+	if err != nil || next.ID != f.feed.ID {
+		t.Errorf("GetNextFeedToFetch() = %v, %v, want the feed fetched longest ago", next.Name, err)
+	}
+}
+
+func TestPostsByIDPrefix(t *testing.T) {
+	ctx := context.Background()
+	_, q := newTestDB(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := newFixture(t, q, now)
+	stranger, err := q.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := f.posts[0]
+	for _, tc := range []struct {
+		user   uuid.UUID
+		prefix string
+		want   int
+	}{
+		{f.user.ID, post.ID.String()[:8], 1},
+		{f.user.ID, post.ID.String(), 1},
+		{f.user.ID, "________", 0},
+		{stranger.ID, post.ID.String()[:8], 0},
+	} {
+		posts, err := q.GetPostsByIDPrefix(ctx, database.GetPostsByIDPrefixParams{UserID: tc.user, IDPrefix: tc.prefix})
+		if err != nil || len(posts) != tc.want {
+			t.Errorf("GetPostsByIDPrefix(%q) = %v posts, %v, want %v", tc.prefix, len(posts), err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const deletePost = `-- name: DeletePost :exec
+DELETE FROM posts
+WHERE id = ?1
+`
+
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePost, id)
+	return err
+}
+
+const getPrunablePostsForFeed = `-- name: GetPrunablePostsForFeed :many
+SELECT ranked.id, ranked.title, ranked.published_at FROM (
+    SELECT
+        posts.id,
+        posts.title,
+        posts.published_at,
+        ROW_NUMBER() OVER (ORDER BY posts.published_at DESC) AS position
+    FROM posts
+    WHERE posts.feed_id = ?1
+) ranked
+WHERE (ranked.published_at < ?2 OR ranked.position > ?3)
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = ranked.id
+    AND post_states.starred
+)
+AND NOT (?4 AND EXISTS (
+    SELECT 1 FROM feed_follows
+    LEFT JOIN post_states
+    ON post_states.post_id = ranked.id
+    AND post_states.user_id = feed_follows.user_id
+    WHERE feed_follows.feed_id = ?1
+    AND post_states.read_at IS NULL
+))
+ORDER BY ranked.published_at
+`
+
+func (q *Queries) GetPrunablePostsForFeed(ctx context.Context, arg database.GetPrunablePostsForFeedParams) ([]database.GetPrunablePostsForFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePostsForFeed,
+		arg.FeedID,
+		arg.Cutoff,
+		arg.KeepNewest,
+		arg.KeepUnread,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetPrunablePostsForFeedRow
+	for rows.Next() {
+		var i database.GetPrunablePostsForFeedRow
+		if err := rows.Scan(&i.ID, &i.Title, &i.PublishedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markPostRead = `-- name: MarkPostRead :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at)
+`
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.PostID, arg.ReadAt)
+	return err
+}
+
+const setPostHidden = `-- name: SetPostHidden :exec
+INSERT INTO post_states (user_id, post_id, hidden)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET hidden = EXCLUDED.hidden
+`
+
+func (q *Queries) SetPostHidden(ctx context.Context, arg database.SetPostHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, setPostHidden, arg.UserID, arg.PostID, arg.Hidden)
+	return err
+}
+
+const setPostStarred = `-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred = EXCLUDED.starred
+`
+
+func (q *Queries) SetPostStarred(ctx context.Context, arg database.SetPostStarredParams) error {
+	_, err := q.db.ExecContext(ctx, setPostStarred, arg.UserID, arg.PostID, arg.Starred)
+	return err
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/ScooballyD/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const addFollowTag = `-- name: AddFollowTag :exec
+INSERT INTO follow_tags (feed_follow_id, name)
+VALUES (?1, ?2)
+ON CONFLICT (feed_follow_id, name) DO NOTHING
+`
+
+func (q *Queries) AddFollowTag(ctx context.Context, arg database.AddFollowTagParams) error {
+	_, err := q.db.ExecContext(ctx, addFollowTag, arg.FeedFollowID, arg.Name)
+	return err
+}
+
+const getFollowTagsForUser = `-- name: GetFollowTagsForUser :many
+SELECT follow_tags.feed_follow_id, follow_tags.name FROM follow_tags
+INNER JOIN feed_follows
+ON follow_tags.feed_follow_id = feed_follows.id
+WHERE feed_follows.user_id = ?1
+ORDER BY follow_tags.name
+`
+
+func (q *Queries) GetFollowTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.FollowTag, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowTagsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.FollowTag
+	for rows.Next() {
+		var i database.FollowTag
+		if err := rows.Scan(&i.FeedFollowID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeFollowTag = `-- name: RemoveFollowTag :execrows
+DELETE FROM follow_tags
+WHERE feed_follow_id = ?1 AND name = ?2
+`
+
+func (q *Queries) RemoveFollowTag(ctx context.Context, arg database.RemoveFollowTagParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeFollowTag, arg.FeedFollowID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,82 @@
+// Package logging builds the slog logger gator writes to, as text or JSON,
+// on stderr or in a log file that is rotated once it grows too large
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+
+	DefaultMaxBytes = 10 << 20
+	DefaultMaxFiles = 5
+)
+
+type Options struct {
+	// debug, info, warn or error, defaults to info
+	Level string
+	// text or json, defaults to text
+	Format string
+	// Log to this file instead of stderr
+	File string
+	// Size a log file may reach before it's rotated
+	MaxBytes int64
+	// Rotated files to keep next to the current one
+	MaxFiles int
+}
+
+// Creates a logger from opts, close the returned closer when done logging
+func New(opts Options) (*slog.Logger, io.Closer, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var w io.Writer = os.Stderr
+	var closer io.Closer = nopCloser{}
+	if opts.File != "" {
+		f, err := OpenRotating(opts.File, opts.MaxBytes, opts.MaxFiles)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, closer = f, f
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		closer.Close()
+		return nil, nil, fmt.Errorf("unknown log format %q, expected %v or %v", opts.Format, FormatText, FormatJSON)
+	}
+	return slog.New(handler), closer, nil
+}
+
+func ParseLevel(raw string) (slog.Level, error) {
+	if raw == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	err := level.UnmarshalText([]byte(raw))
+	if err != nil {
+		return 0, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", raw)
+	}
+	return level, nil
+}
+
+// Closer for stderr, which stays open
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Log file that is moved aside once it reaches maxBytes: gator.log becomes
+// gator.log.1, the old gator.log.1 becomes gator.log.2 and so on, the oldest
+// beyond maxFiles is deleted
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func OpenRotating(path string, maxBytes int64, maxFiles int) (*RotatingFile, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create log dir: %v", err)
+	}
+
+	r := &RotatingFile{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	err = r.open()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open log file: %v", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to open log file: %v", err)
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// a failed rotation can leave no file open, try again
+	if r.file == nil {
+		err := r.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+	// a single entry larger than the limit still goes into a file of its own
+	var rotateErr error
+	if r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		rotateErr = r.rotate()
+		if r.file == nil {
+			return 0, rotateErr
+		}
+	}
+	// when rotating failed the entry still goes into the current file
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+// Moves the files aside and opens a new one, when that fails the current
+// file is opened again so logging carries on in it
+func (r *RotatingFile) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return errors.Join(fmt.Errorf("unable to close log file: %v", err), r.open())
+	}
+	err = r.shift()
+	if err != nil {
+		return errors.Join(err, r.open())
+	}
+	return r.open()
+}
+
+func (r *RotatingFile) shift() error {
+	err := os.Remove(r.backup(r.maxFiles))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unable to remove old log file: %v", err)
+	}
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		err = os.Rename(r.backup(i), r.backup(i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("unable to rotate log file: %v", err)
+		}
+	}
+	err = os.Rename(r.path, r.backup(1))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unable to rotate log file: %v", err)
+	}
+	return nil
+}
+
+func (r *RotatingFile) backup(n int) string {
+	return fmt.Sprintf("%v.%v", r.path, n)
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
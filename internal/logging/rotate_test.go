@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gator.log")
+	r, err := OpenRotating(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, entry := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err = r.Write([]byte(entry))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for file, want := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		if got := readFile(t, file); got != want {
+			t.Errorf("%v = %q, want %q", filepath.Base(file), got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("kept more than 2 old files")
+	}
+}
+
+func TestRotatingFileFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gator.log")
+	r, err := OpenRotating(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// a directory in the way of gator.log.1 can't be removed
+	err = os.MkdirAll(filepath.Join(path+".1", "blocker"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Write([]byte("first\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Write([]byte("second\n"))
+	if err == nil {
+		t.Fatal("rotating into a directory reported no error")
+	}
+
+	// logging carries on in the current file, and rotates once the way is clear
+	_, err = r.Write([]byte("third\n"))
+	if err == nil || strings.Contains(err.Error(), "closed") {
+		t.Fatalf("writing after a failed rotation returned %v, want the rotation error again", err)
+	}
+	if got := readFile(t, path); got != "first\nsecond\nthird\n" {
+		t.Errorf("gator.log = %q, want every entry", got)
+	}
+	err = os.RemoveAll(path + ".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Write([]byte("fourth\n"))
+	if err != nil {
+		t.Fatalf("writing once the rotation can succeed: %v", err)
+	}
+	if got := readFile(t, path); got != "fourth\n" {
+		t.Errorf("gator.log = %q after rotating, want only the newest entry", got)
+	}
+}
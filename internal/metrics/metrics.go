@@ -0,0 +1,243 @@
+// Package metrics keeps counters, gauges and histograms in memory and serves
+// them in the Prometheus text format, enough for gator without pulling in the
+// whole Prometheus client
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Upper bounds in seconds, for request and query latency
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Upper bounds in bytes, 1KiB up to 16MiB
+var ByteBuckets = ExponentialBuckets(1024, 4, 8)
+
+// Creates count buckets starting at start, each factor times the one before
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counts something that only goes up, ex: fetches
+type Counter struct {
+	f *family
+}
+
+// Holds a value that goes up and down, ex: feeds waiting to be fetched
+type Gauge struct {
+	f *family
+}
+
+// Counts observations into buckets, ex: how long fetches took
+type Histogram struct {
+	f *family
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	// counter and gauge value, or the sum of observations
+	value  float64
+	counts []uint64
+	count  uint64
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(name, help, "counter", labels, nil)}
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(name, help, "gauge", labels, nil)}
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{r.register(name, help, "histogram", labels, buckets)}
+}
+
+func (r *Registry) register(name, help, kind string, labels []string, buckets []float64) *family {
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  map[string]*series{},
+	}
+	if len(labels) == 0 {
+		// without labels there's only one series, shown as 0 until something happens
+		f.series[""] = &series{counts: make([]uint64, len(buckets))}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.families {
+		if other.name == name {
+			panic("metrics: " + name + " registered twice")
+		}
+	}
+	r.families = append(r.families, f)
+	return f
+}
+
+// Adds 1 to the series with the given label values
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		panic("metrics: " + c.f.name + " can't go down")
+	}
+	c.f.with(values, func(s *series) {
+		s.value += v
+	})
+}
+
+func (g *Gauge) Set(v float64, values ...string) {
+	g.f.with(values, func(s *series) {
+		s.value = v
+	})
+}
+
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.f.with(values, func(s *series) {
+		for i, bound := range h.f.buckets {
+			if v <= bound {
+				s.counts[i]++
+			}
+		}
+		s.value += v
+		s.count++
+	})
+}
+
+func (f *family) with(values []string, update func(s *series)) {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %v takes %v label values, got %v", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, exist := f.series[key]
+	if !exist {
+		s = &series{
+			values: append([]string(nil), values...),
+			counts: make([]uint64, len(f.buckets)),
+		}
+		f.series[key] = s
+	}
+	update(s)
+}
+
+// Writes every metric in the Prometheus text format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	families := append([]*family(nil), r.families...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (f *family) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %v %v\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %v %v\n", f.name, f.kind)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != "histogram" {
+			fmt.Fprintf(w, "%v%v %v\n", f.name, f.labelPairs(s.values, ""), formatFloat(s.value))
+			continue
+		}
+		for i, bound := range f.buckets {
+			le := formatFloat(bound)
+			fmt.Fprintf(w, "%v_bucket%v %v\n", f.name, f.labelPairs(s.values, le), s.counts[i])
+		}
+		fmt.Fprintf(w, "%v_bucket%v %v\n", f.name, f.labelPairs(s.values, "+Inf"), s.count)
+		fmt.Fprintf(w, "%v_sum%v %v\n", f.name, f.labelPairs(s.values, ""), formatFloat(s.value))
+		fmt.Fprintf(w, "%v_count%v %v\n", f.name, f.labelPairs(s.values, ""), s.count)
+	}
+}
+
+// Formats {name="value",...}, with the le label of a histogram bucket last
+func (f *family) labelPairs(values []string, le string) string {
+	var pairs []string
+	for i, name := range f.labels {
+		pairs = append(pairs, name+`="`+escapeValue(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func escapeValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(w)
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	fetches := r.NewCounter("fetches_total", "Fetches by status", "status")
+	overdue := r.NewGauge("overdue", "Feeds\nbehind")
+	seconds := r.NewHistogram("seconds", "Durations", []float64{0.5, 1})
+
+	fetches.Inc("200")
+	fetches.Add(2, "200")
+	fetches.Inc(`a"b\c`)
+	overdue.Set(3)
+	overdue.Set(1)
+	seconds.Observe(0.25)
+	seconds.Observe(0.75)
+	seconds.Observe(4)
+
+	var b strings.Builder
+	err := r.Write(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP fetches_total Fetches by status
+# TYPE fetches_total counter
+fetches_total{status="200"} 3
+fetches_total{status="a\"b\\c"} 1
+# HELP overdue Feeds\nbehind
+# TYPE overdue gauge
+overdue 1
+# HELP seconds Durations
+# TYPE seconds histogram
+seconds_bucket{le="0.5"} 1
+seconds_bucket{le="1"} 2
+seconds_bucket{le="+Inf"} 3
+seconds_sum 5
+seconds_count 3
+`
+	if b.String() != want {
+		t.Errorf("Write() =\n%v\nwant\n%v", b.String(), want)
+	}
+}
+
+func TestExponentialBuckets(t *testing.T) {
+	got := ExponentialBuckets(1024, 4, 3)
+	if len(got) != 3 || got[0] != 1024 || got[1] != 4096 || got[2] != 16384 {
+		t.Errorf("ExponentialBuckets(1024, 4, 3) = %v", got)
+	}
+}
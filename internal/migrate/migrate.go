@@ -0,0 +1,328 @@
+// Package migrate applies goose migrations from sql/schema or sql/sqlite/schema,
+// keeping goose's version table so databases set up with goose carry on working
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const versionTable = "goose_db_version"
+
+type Migration struct {
+	Version int64
+	// File name without the version and extension, ex: "users"
+	Name string
+	File string
+	Up   string
+	Down string
+}
+
+// A migration and whether the database has it
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// How a database keeps goose's version table, the same way goose does it
+type Dialect struct {
+	createTable string
+	tableExists string
+}
+
+var Postgres = Dialect{
+	createTable: `CREATE TABLE ` + versionTable + ` (
+	id SERIAL PRIMARY KEY,
+	version_id BIGINT NOT NULL,
+	is_applied BOOLEAN NOT NULL,
+	tstamp TIMESTAMP DEFAULT now()
+)`,
+	tableExists: "SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = $1)",
+}
+
+var SQLite = Dialect{
+	createTable: `CREATE TABLE ` + versionTable + ` (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	version_id INTEGER NOT NULL,
+	is_applied INTEGER NOT NULL,
+	tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`,
+	tableExists: "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)",
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// Reads the migrations from fsys, named like "001_users.sql"
+func New(db *sql.DB, dialect Dialect, fsys fs.FS) (*Migrator, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{db: db, dialect: dialect}
+	seen := map[int64]string{}
+	for _, file := range files {
+		mig, err := parse(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		if other, dup := seen[mig.Version]; dup {
+			return nil, fmt.Errorf("%v and %v have the same version", other, file)
+		}
+		seen[mig.Version] = file
+		m.migrations = append(m.migrations, mig)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	return m, nil
+}
+
+func parse(fsys fs.FS, file string) (Migration, error) {
+	prefix, name, found := strings.Cut(strings.TrimSuffix(path.Base(file), ".sql"), "_")
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if !found || err != nil || version < 1 {
+		return Migration{}, fmt.Errorf("%v: migrations are named like 001_name.sql", file)
+	}
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	// statements belong to the last +goose annotation above them
+	var up, down strings.Builder
+	var section *strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			section = &up
+			continue
+		case "-- +goose Down":
+			section = &down
+			continue
+		}
+		if section != nil {
+			section.WriteString(line + "\n")
+		}
+	}
+	if section == nil {
+		return Migration{}, fmt.Errorf("%v: missing -- +goose Up", file)
+	}
+	return Migration{
+		Version: version,
+		Name:    name,
+		File:    file,
+		Up:      up.String(),
+		Down:    down.String(),
+	}, nil
+}
+
+// Version of the oldest migration gator knows about, later than 1 when
+// the early history was squashed into one file
+func (m *Migrator) First() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[0].Version
+}
+
+// Version of the newest migration gator knows about
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Creates goose's version table if the database doesn't have one yet
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	var exists bool
+	err := m.db.QueryRowContext(ctx, m.dialect.tableExists, versionTable).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, m.dialect.createTable)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO "+versionTable+" (version_id, is_applied) VALUES (0, true)")
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// The version the database is at, worked out the way goose does it:
+// the newest row for each version decides whether it's applied
+func (m *Migrator) Current(ctx context.Context) (int64, error) {
+	err := m.ensureTable(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT version_id, is_applied FROM "+versionTable+" ORDER BY id DESC")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	skip := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var applied bool
+		err = rows.Scan(&version, &applied)
+		if err != nil {
+			return 0, err
+		}
+		if skip[version] {
+			continue
+		}
+		if applied {
+			return version, nil
+		}
+		skip[version] = true
+	}
+	return 0, rows.Err()
+}
+
+// Applies every migration newer than the database, returning the ones applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Rolls back the newest applied migration
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	current, err := m.Current(ctx)
+	if err != nil {
+		return Migration{}, err
+	}
+	if current == 0 {
+		return Migration{}, fmt.Errorf("no migrations to roll back")
+	}
+	mig, ok := m.find(current)
+	if !ok {
+		return Migration{}, fmt.Errorf("the database is at version %v, which gator has no migration for", current)
+	}
+	return mig, m.apply(ctx, mig, false)
+}
+
+// Migrates up or down to version, returning the migrations applied or rolled back in order
+func (m *Migrator) To(ctx context.Context, version int64) ([]Migration, error) {
+	if version != 0 {
+		if _, ok := m.find(version); !ok {
+			return nil, fmt.Errorf("no migration with version %v", version)
+		}
+	}
+	current, err := m.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	if version >= current {
+		for _, mig := range m.migrations {
+			if mig.Version <= current || mig.Version > version {
+				continue
+			}
+			err = m.apply(ctx, mig, true)
+			if err != nil {
+				return done, err
+			}
+			done = append(done, mig)
+		}
+		return done, nil
+	}
+
+	if _, ok := m.find(current); !ok {
+		return nil, fmt.Errorf("the database is at version %v, which gator has no migration for", current)
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if mig.Version > current || mig.Version <= version {
+			continue
+		}
+		err = m.apply(ctx, mig, false)
+		if err != nil {
+			return done, err
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Lists every migration with when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	err := m.ensureTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []Status
+	for _, mig := range m.migrations {
+		st := Status{Migration: mig}
+		var applied sql.NullTime
+		err = m.db.QueryRowContext(ctx,
+			"SELECT tstamp, is_applied FROM "+versionTable+" WHERE version_id = $1 ORDER BY id DESC LIMIT 1",
+			mig.Version).Scan(&applied, &st.Applied)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		st.AppliedAt = applied.Time
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+// Runs one migration and records it in a single transaction, so a failed
+// migration leaves the database as it was
+func (m *Migrator) apply(ctx context.Context, mig Migration, up bool) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements, record := mig.Down, "DELETE FROM "+versionTable+" WHERE version_id = $1"
+	if up {
+		statements, record = mig.Up, "INSERT INTO "+versionTable+" (version_id, is_applied) VALUES ($1, true)"
+	}
+	if strings.TrimSpace(statements) != "" {
+		_, err = tx.ExecContext(ctx, statements)
+		if err != nil {
+			return fmt.Errorf("%v: %w", mig.File, err)
+		}
+	}
+	_, err = tx.ExecContext(ctx, record, mig.Version)
+	if err != nil {
+		return fmt.Errorf("%v: %w", mig.File, err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,223 @@
+// Package output renders command results, a slice of structs, as an aligned
+// table, JSON, JSON lines, CSV or YAML. Columns and keys come from the json
+// tag of each exported field so every format uses the same names
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	Table = "table"
+	JSON  = "json"
+	JSONL = "jsonl"
+	CSV   = "csv"
+	YAML  = "yaml"
+)
+
+var Formats = []string{Table, JSON, JSONL, CSV, YAML}
+
+func Valid(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, expected one of %v", format, strings.Join(Formats, ", "))
+}
+
+type column struct {
+	name  string
+	index int
+}
+
+// Render writes rows in the given format, rows has to be a slice of structs
+func Render(w io.Writer, format string, rows any) error {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("output: expected a slice of structs, got %T", rows)
+	}
+	if v.IsNil() {
+		// an empty result is [] rather than null
+		v = reflect.MakeSlice(v.Type(), 0, 0)
+		rows = v.Interface()
+	}
+
+	switch format {
+	case Table:
+		return renderTable(w, v)
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case JSONL:
+		enc := json.NewEncoder(w)
+		for i := 0; i < v.Len(); i++ {
+			if err := enc.Encode(v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CSV:
+		return renderCSV(w, v)
+	case YAML:
+		return renderYAML(w, rows)
+	}
+	return Valid(format)
+}
+
+func columns(t reflect.Type) []column {
+	var cols []column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		cols = append(cols, column{name: name, index: i})
+	}
+	return cols
+}
+
+func renderTable(w io.Writer, v reflect.Value) error {
+	cols := columns(v.Type().Elem())
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	headers := make([]string, len(cols))
+	for i, c := range cols {
+		headers[i] = strings.ToUpper(strings.ReplaceAll(c.name, "_", " "))
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for i := 0; i < v.Len(); i++ {
+		cells := make([]string, len(cols))
+		for j, c := range cols {
+			// tabs and newlines would break the alignment
+			cells[j] = strings.Join(strings.Fields(cell(v.Index(i).Field(c.index), time.DateTime)), " ")
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Goes through JSON so YAML keys and values match the json output exactly,
+// JSON is valid YAML so the node tree keeps the field order
+func renderYAML(w io.Writer, rows any) error {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	err = yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return err
+	}
+	plainStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	err = enc.Encode(&doc)
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Drops the JSON quoting and flow style so the encoder picks block style,
+// words older YAML parsers read as booleans stay quoted
+func plainStyle(n *yaml.Node) {
+	n.Style = 0
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		switch strings.ToLower(n.Value) {
+		case "y", "n", "yes", "no", "on", "off":
+			n.Style = yaml.DoubleQuotedStyle
+		}
+	}
+	for _, c := range n.Content {
+		plainStyle(c)
+	}
+}
+
+func renderCSV(w io.Writer, v reflect.Value) error {
+	cols := columns(v.Type().Elem())
+	cw := csv.NewWriter(w)
+
+	headers := make([]string, len(cols))
+	for i, c := range cols {
+		headers[i] = c.name
+	}
+	if err := cw.Write(headers); err != nil {
+		return err
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		record := make([]string, len(cols))
+		for j, c := range cols {
+			record[j] = cell(v.Index(i).Field(c.index), time.RFC3339)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Formats a single value for the flat formats, lists are joined with "; "
+func cell(f reflect.Value, timeLayout string) string {
+	switch val := f.Interface().(type) {
+	case time.Time:
+		if val.IsZero() {
+			return ""
+		}
+		return val.Format(timeLayout)
+	case *time.Time:
+		if val == nil {
+			return ""
+		}
+		return val.Format(timeLayout)
+	case fmt.Stringer:
+		return val.String()
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		return f.String()
+	case reflect.Bool:
+		return strconv.FormatBool(f.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(f.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(f.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'f', -1, 64)
+	case reflect.Pointer:
+		if f.IsNil() {
+			return ""
+		}
+		return cell(f.Elem(), timeLayout)
+	case reflect.Slice:
+		parts := make([]string, f.Len())
+		for i := range parts {
+			parts[i] = cell(f.Index(i), timeLayout)
+		}
+		return strings.Join(parts, "; ")
+	}
+	return fmt.Sprint(f.Interface())
+}
@@ -0,0 +1,317 @@
+// Package readability finds the main article in a web page, loosely following
+// the scoring used by Arc90's readability: paragraphs score their ancestors by
+// text length and comma count, class and id names nudge the score up or down,
+// and link heavy blocks are penalised
+package readability
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ScooballyD/gator/internal/render"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var ErrNoArticle = errors.New("no article content found")
+
+var (
+	unlikelyNames = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|ad-break|agegate|pagination|pager`)
+	maybeNames    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeNames = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// Elements that never hold article text
+var junkTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Form: true, atom.Nav: true, atom.Aside: true, atom.Footer: true,
+	atom.Header: true, atom.Button: true, atom.Input: true, atom.Select: true,
+	atom.Textarea: true, atom.Svg: true, atom.Link: true, atom.Meta: true,
+}
+
+// Minimum amount of text a paragraph needs before it counts
+const minParagraphLen = 25
+
+type Article struct {
+	Title string
+	// Sanitized HTML of the article body
+	Content string
+}
+
+// Extract parses an HTML page and returns its main article, relative links
+// and images are resolved against base
+func Extract(page io.Reader, base *url.URL) (Article, error) {
+	doc, err := html.Parse(page)
+	if err != nil {
+		return Article{}, err
+	}
+
+	article := Article{Title: pageTitle(doc)}
+	body := find(doc, atom.Body)
+	if body == nil {
+		return article, ErrNoArticle
+	}
+	clean(body)
+
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+	for _, p := range findAll(body, atom.P, atom.Pre, atom.Td, atom.Blockquote) {
+		text := innerText(p)
+		if len(text) < minParagraphLen {
+			continue
+		}
+		points := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+
+		parent := p.Parent
+		for level := 0; parent != nil && parent.Type == html.ElementNode && level < 3; level++ {
+			if _, ok := scores[parent]; !ok {
+				scores[parent] = initialScore(parent)
+				candidates = append(candidates, parent)
+			}
+			// the direct parent gets full credit, further ancestors less
+			switch level {
+			case 0:
+				scores[parent] += points
+			case 1:
+				scores[parent] += points / 2
+			default:
+				scores[parent] += points / float64(level*3)
+			}
+			parent = parent.Parent
+		}
+	}
+
+	var top *html.Node
+	for _, c := range candidates {
+		scores[c] *= 1 - linkDensity(c)
+		if top == nil || scores[c] > scores[top] {
+			top = c
+		}
+	}
+	if top == nil {
+		return article, ErrNoArticle
+	}
+
+	content := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	threshold := math.Max(10, scores[top]*0.2)
+	for _, sib := range siblings(top) {
+		if sib == top || keepSibling(sib, scores, threshold) {
+			sib.Parent.RemoveChild(sib)
+			content.AppendChild(sib)
+		}
+	}
+	resolveURLs(content, base)
+
+	var sb strings.Builder
+	for c := content.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&sb, c)
+	}
+	article.Content = render.Sanitize(sb.String())
+	if len(innerText(content)) < minParagraphLen*4 {
+		return article, ErrNoArticle
+	}
+	return article, nil
+}
+
+// Snapshot of a node's siblings, including itself, safe to detach while ranging
+func siblings(n *html.Node) []*html.Node {
+	if n.Parent == nil {
+		return []*html.Node{n}
+	}
+	var out []*html.Node
+	for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
+		out = append(out, c)
+	}
+	return out
+}
+
+// Blocks next to the top candidate often hold more of the article,
+// like a lead paragraph outside the main content div
+func keepSibling(n *html.Node, scores map[*html.Node]float64, threshold float64) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if score, ok := scores[n]; ok && score >= threshold {
+		return true
+	}
+	if n.DataAtom != atom.P {
+		return false
+	}
+	text := innerText(n)
+	density := linkDensity(n)
+	if len(text) > 80 && density < 0.25 {
+		return true
+	}
+	return len(text) > 0 && len(text) <= 80 && density == 0 && strings.ContainsAny(text, ".!?")
+}
+
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.DataAtom {
+	case atom.Article:
+		score += 10
+	case atom.Div, atom.Section, atom.Main:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	return score
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			weight -= 25
+		}
+		if positiveNames.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// Removes elements that can't be part of the article before scoring
+func clean(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.CommentNode:
+			n.RemoveChild(c)
+		case html.ElementNode:
+			names := attr(c, "class") + " " + attr(c, "id")
+			unlikely := unlikelyNames.MatchString(names) && !maybeNames.MatchString(names) &&
+				c.DataAtom != atom.Body && c.DataAtom != atom.A && c.DataAtom != atom.Article
+			if junkTags[c.DataAtom] || unlikely || isHidden(c) {
+				n.RemoveChild(c)
+			} else {
+				clean(c)
+			}
+		}
+		c = next
+	}
+}
+
+func isHidden(n *html.Node) bool {
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+	for _, a := range n.Attr {
+		if a.Key == "hidden" || (a.Key == "aria-hidden" && a.Val == "true") {
+			return true
+		}
+	}
+	return false
+}
+
+// Share of a node's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(innerText(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	for _, a := range findAll(n, atom.A) {
+		linked += len(innerText(a))
+	}
+	return float64(linked) / float64(total)
+}
+
+func resolveURLs(n *html.Node, base *url.URL) {
+	if base == nil {
+		return
+	}
+	for _, el := range findAll(n, atom.A, atom.Img) {
+		for i, a := range el.Attr {
+			if a.Key != "href" && a.Key != "src" {
+				continue
+			}
+			ref, err := url.Parse(strings.TrimSpace(a.Val))
+			if err != nil {
+				continue
+			}
+			el.Attr[i].Val = base.ResolveReference(ref).String()
+		}
+	}
+}
+
+func pageTitle(doc *html.Node) string {
+	if h1s := findAll(doc, atom.H1); len(h1s) == 1 {
+		if t := innerText(h1s[0]); t != "" {
+			return t
+		}
+	}
+	if t := find(doc, atom.Title); t != nil {
+		return innerText(t)
+	}
+	return ""
+}
+
+func find(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := find(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func findAll(n *html.Node, atoms ...atom.Atom) []*html.Node {
+	var out []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				for _, a := range atoms {
+					if c.DataAtom == a {
+						out = append(out, c)
+						break
+					}
+				}
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return out
+}
+
+func innerText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package render
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Elements kept as they are, anything else is unwrapped to its children
+var allowedTags = map[atom.Atom]bool{
+	atom.A: true, atom.Abbr: true, atom.B: true, atom.Blockquote: true, atom.Br: true,
+	atom.Code: true, atom.Dd: true, atom.Del: true, atom.Dl: true, atom.Dt: true,
+	atom.Em: true, atom.Figcaption: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Hr: true, atom.I: true,
+	atom.Img: true, atom.Li: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+	atom.Q: true, atom.S: true, atom.Small: true, atom.Strong: true, atom.Sub: true,
+	atom.Sup: true, atom.Table: true, atom.Tbody: true, atom.Td: true, atom.Th: true,
+	atom.Thead: true, atom.Tr: true, atom.U: true, atom.Ul: true,
+}
+
+// Elements dropped together with everything inside them
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Object: true,
+	atom.Embed: true, atom.Form: true, atom.Input: true, atom.Button: true,
+	atom.Select: true, atom.Textarea: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Math: true, atom.Link: true, atom.Meta: true,
+	atom.Base: true, atom.Head: true, atom.Title: true, atom.Frame: true,
+	atom.Frameset: true, atom.Audio: true, atom.Video: true, atom.Canvas: true,
+}
+
+// Attributes allowed per element, urls are also checked for a safe scheme
+var allowedAttrs = map[atom.Atom][]string{
+	atom.A:   {"href", "title"},
+	atom.Img: {"src", "alt", "title"},
+	atom.Td:  {"colspan", "rowspan"},
+	atom.Th:  {"colspan", "rowspan"},
+}
+
+// Sanitize strips everything but a small allowlist of formatting markup from
+// feed supplied HTML: no scripts, styles, event handlers or javascript: links
+func Sanitize(src string) string {
+	nodes, err := parse(src)
+	if err != nil {
+		return html.EscapeString(src)
+	}
+
+	var sb strings.Builder
+	for _, n := range nodes {
+		html.Render(&sb, n)
+	}
+	return sb.String()
+}
+
+// Parses an HTML fragment and sanitizes the resulting nodes
+func parse(src string) ([]*html.Node, error) {
+	body := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(src), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	sanitizeChildren(body)
+
+	var out []*html.Node
+	for c := body.FirstChild; c != nil; {
+		next := c.NextSibling
+		body.RemoveChild(c)
+		out = append(out, c)
+		c = next
+	}
+	return out, nil
+}
+
+func sanitizeChildren(parent *html.Node) {
+	for c := parent.FirstChild; c != nil; {
+		next := c.NextSibling
+
+		switch c.Type {
+		case html.TextNode:
+		case html.ElementNode:
+			switch {
+			case droppedTags[c.DataAtom]:
+				parent.RemoveChild(c)
+			case allowedTags[c.DataAtom]:
+				c.Attr = safeAttrs(c)
+				sanitizeChildren(c)
+			default:
+				// unwrap: sanitize the children, then hoist them into c's place
+				sanitizeChildren(c)
+				for gc := c.FirstChild; gc != nil; {
+					gnext := gc.NextSibling
+					c.RemoveChild(gc)
+					parent.InsertBefore(gc, c)
+					gc = gnext
+				}
+				parent.RemoveChild(c)
+			}
+		default:
+			// comments, doctypes and the like
+			parent.RemoveChild(c)
+		}
+
+		c = next
+	}
+}
+
+func safeAttrs(n *html.Node) []html.Attribute {
+	var kept []html.Attribute
+	for _, a := range n.Attr {
+		if a.Namespace != "" || !contains(allowedAttrs[n.DataAtom], a.Key) {
+			continue
+		}
+		if (a.Key == "href" || a.Key == "src") && !safeURL(a.Val) {
+			continue
+		}
+		kept = append(kept, html.Attribute{Key: a.Key, Val: a.Val})
+	}
+	return kept
+}
+
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,280 @@
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const defaultWidth = 80
+
+// Options controls how HTML is laid out as terminal text
+type Options struct {
+	// Width to wrap lines at, defaults to 80
+	Width int
+	// Lines to show before truncating, 0 shows everything
+	MaxLines int
+	// Indent added in front of every line
+	Indent string
+}
+
+type listState struct {
+	ordered bool
+	next    int
+}
+
+type textRenderer struct {
+	opts   Options
+	lines  []string
+	para   strings.Builder
+	marker string
+	tight  bool
+	indent int
+	quote  int
+	lists  []listState
+	links  []string
+	pre    bool
+	// text of the links being walked, para can't be used for it since a
+	// block inside a link flushes it
+	linkText []*strings.Builder
+}
+
+// Text sanitizes HTML and lays it out as wrapped plain text: paragraphs are
+// separated by blank lines, lists get bullets or numbers and links are turned
+// into numbered footnotes
+func Text(src string, opts Options) string {
+	if opts.Width <= 0 {
+		opts.Width = defaultWidth
+	}
+
+	nodes, err := parse(src)
+	if err != nil {
+		return src
+	}
+
+	r := &textRenderer{opts: opts}
+	for _, n := range nodes {
+		r.walk(n)
+	}
+	r.flush()
+
+	lines := r.lines
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if opts.MaxLines > 0 && len(lines) > opts.MaxLines {
+		lines = append(lines[:opts.MaxLines:opts.MaxLines], "…")
+	}
+
+	// only list footnotes for links that survived truncation
+	shown := strings.Join(lines, "\n")
+	var notes []string
+	for i, link := range r.links {
+		ref := "[" + strconv.Itoa(i+1) + "]"
+		if strings.Contains(shown, ref) {
+			notes = append(notes, ref+" "+link)
+		}
+	}
+	if len(notes) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, notes...)
+	}
+
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = opts.Indent + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r *textRenderer) walk(n *html.Node) {
+	if n.Type == html.TextNode {
+		if r.pre {
+			r.write(n.Data)
+		} else {
+			// source newlines are just whitespace, only <br> breaks a line
+			r.write(collapseSpace(n.Data))
+		}
+		return
+	}
+	if n.Type != html.ElementNode {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		r.write("\n")
+	case atom.Hr:
+		r.flush()
+		r.addLine(strings.Repeat("-", min(r.opts.Width, 20)))
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			r.write("[image: " + alt + "]")
+		} else {
+			r.write("[image]")
+		}
+	case atom.A:
+		r.linkText = append(r.linkText, &strings.Builder{})
+		r.walkChildren(n)
+		text := strings.TrimSpace(r.linkText[len(r.linkText)-1].String())
+		r.linkText = r.linkText[:len(r.linkText)-1]
+		href := attr(n, "href")
+		if href != "" && text != href {
+			r.links = append(r.links, href)
+			r.write("[" + strconv.Itoa(len(r.links)) + "]")
+		}
+	case atom.Ul, atom.Ol:
+		r.flush()
+		r.gap()
+		r.lists = append(r.lists, listState{ordered: n.DataAtom == atom.Ol, next: 1})
+		r.indent += 2
+		r.walkChildren(n)
+		r.flush()
+		r.indent -= 2
+		r.lists = r.lists[:len(r.lists)-1]
+		r.tight = false
+	case atom.Li:
+		r.flush()
+		r.marker = "• "
+		if len(r.lists) > 0 {
+			l := &r.lists[len(r.lists)-1]
+			if l.ordered {
+				r.marker = fmt.Sprintf("%d. ", l.next)
+				l.next++
+			}
+		}
+		r.tight = true
+		r.walkChildren(n)
+		r.flush()
+	case atom.Blockquote:
+		r.flush()
+		r.quote++
+		r.walkChildren(n)
+		r.flush()
+		r.quote--
+	case atom.Pre:
+		r.flush()
+		r.pre = true
+		r.walkChildren(n)
+		r.pre = false
+		raw := r.para.String()
+		r.para.Reset()
+		r.gap()
+		for _, l := range strings.Split(strings.Trim(raw, "\n"), "\n") {
+			r.addLine(r.prefix() + "    " + l)
+		}
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Table, atom.Tr, atom.Dl, atom.Dt, atom.Dd, atom.Figcaption:
+		r.flush()
+		r.walkChildren(n)
+		r.flush()
+	case atom.Td, atom.Th:
+		r.walkChildren(n)
+		r.write("  ")
+	default:
+		r.walkChildren(n)
+	}
+}
+
+func (r *textRenderer) walkChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.walk(c)
+	}
+}
+
+// Adds inline text to the paragraph and to any link it is part of
+func (r *textRenderer) write(text string) {
+	r.para.WriteString(text)
+	for _, b := range r.linkText {
+		b.WriteString(text)
+	}
+}
+
+func (r *textRenderer) prefix() string {
+	return strings.Repeat("> ", r.quote) + strings.Repeat(" ", r.indent)
+}
+
+func (r *textRenderer) addLine(l string) {
+	r.lines = append(r.lines, strings.TrimRight(l, " "))
+}
+
+// Separates blocks with a blank line, list items stay together
+func (r *textRenderer) gap() {
+	if len(r.lines) > 0 && r.lines[len(r.lines)-1] != "" && !r.tight {
+		r.lines = append(r.lines, "")
+	}
+}
+
+// Wraps the pending inline text into lines
+func (r *textRenderer) flush() {
+	text := r.para.String()
+	r.para.Reset()
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	first := r.prefix() + r.marker
+	rest := r.prefix() + strings.Repeat(" ", utf8.RuneCountInString(r.marker))
+	width := r.opts.Width - utf8.RuneCountInString(rest)
+	if width < 20 {
+		width = 20
+	}
+
+	r.gap()
+	for i, l := range wrap(text, width) {
+		if i == 0 {
+			r.addLine(first + l)
+		} else {
+			r.addLine(rest + l)
+		}
+	}
+	r.marker = ""
+}
+
+// Replaces runs of whitespace with a single space, keeping a leading or
+// trailing space so words in neighbouring text nodes stay apart
+func collapseSpace(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+	out := strings.Join(fields, " ")
+	if first, _ := utf8.DecodeRuneInString(s); unicode.IsSpace(first) {
+		out = " " + out
+	}
+	if last, _ := utf8.DecodeLastRuneInString(s); unicode.IsSpace(last) {
+		out += " "
+	}
+	return out
+}
+
+// Greedy word wrap, explicit newlines from <br> are kept
+func wrap(text string, width int) []string {
+	var out []string
+	for _, hard := range strings.Split(text, "\n") {
+		words := strings.Fields(hard)
+		if len(words) == 0 {
+			continue
+		}
+		line := words[0]
+		for _, w := range words[1:] {
+			if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(w) > width {
+				out = append(out, line)
+				line = w
+				continue
+			}
+			line += " " + w
+		}
+		out = append(out, line)
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		opts Options
+		want string
+	}{
+		{
+			name: "paragraphs",
+			src:  "<p>one</p>\n<p>two</p>",
+			want: "one\n\ntwo",
+		},
+		{
+			name: "lists",
+			src:  "<ul><li>a</li><li>b</li></ul><ol><li>x</li><li>y</li></ol>",
+			want: "  • a\n  • b\n\n  1. x\n  2. y",
+		},
+		{
+			name: "links become footnotes unless the text is the url",
+			src:  `<p>see <a href="https://e.com/x">this post</a> and <a href="https://e.com/y">https://e.com/y</a></p>`,
+			opts: Options{Width: 30},
+			want: "see this post[1] and\nhttps://e.com/y\n\n[1] https://e.com/x",
+		},
+		{
+			name: "link around blocks",
+			src:  `<a href="https://e.com/x"><p>first</p><p>second</p></a> after`,
+			want: "first\n\nsecond\n\n[1] after\n\n[1] https://e.com/x",
+		},
+		{
+			name: "scripts are dropped",
+			src:  "<p>hello<script>alert(1)</script> <b>world</b></p>",
+			want: "hello world",
+		},
+		{
+			name: "quotes and preformatted text",
+			src:  "<blockquote>quoted</blockquote><pre>a  b\n  c</pre>",
+			want: "> quoted\n\n    a  b\n      c",
+		},
+		{
+			name: "wrapping",
+			src:  "one two three four five six seven eight nine ten",
+			opts: Options{Width: 20},
+			want: "one two three four\nfive six seven eight\nnine ten",
+		},
+		{
+			name: "truncation drops footnotes for hidden links",
+			src:  `<p>a</p><p>b</p><p>c <a href="https://e.com">c</a></p>`,
+			opts: Options{MaxLines: 2, Indent: "  "},
+			want: "  a\n\n  …",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Text(tc.src, tc.opts)
+			if got != tc.want {
+				t.Errorf("Text(%q) =\n%q\nwant\n%q", tc.src, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	got := Sanitize(`<p onclick="x()">hi <a href="javascript:alert(1)">x</a> <a href="https://e.com" target="_blank">y</a><script>bad()</script><span>z</span></p>`)
+	for _, bad := range []string{"onclick", "javascript:", "script", "bad()", "target", "span"} {
+		if strings.Contains(got, bad) {
+			t.Errorf("Sanitize() = %q, still contains %q", got, bad)
+		}
+	}
+	for _, kept := range []string{"<p>", `<a href="https://e.com">y</a>`, "z"} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("Sanitize() = %q, lost %q", got, kept)
+		}
+	}
+}
@@ -4,51 +4,60 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/ScooballyD/gator/internal/apperr"
 	"github.com/ScooballyD/gator/internal/config"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
-	cfg, err := config.Read()
+	globals, args, err := config.ParseGlobalFlags(os.Args[1:])
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exit(err, false)
+	}
+
+	cmds := config.NewCommands()
+	if cmds.WantsHelp(args) {
+		// help works without a config file, it only supplies flag defaults
+		cfg, _ := config.Read()
+		err = cmds.Help(cfg, args)
+		if err != nil {
+			exit(err, globals.Debug)
+		}
+		if len(args) < 1 {
+			os.Exit(2)
+		}
+		return
 	}
 
-	s, err := cfg.NewState()
+	cfg, err := config.Read()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exit(err, globals.Debug)
 	}
 
-	cmds := config.Commands{
-		Library: make(map[string]func(*config.State, config.Command) error),
+	s, err := cfg.NewState(cmds.NeedsSchema(args))
+	if err != nil {
+		exit(err, globals.Debug)
 	}
-	cmds.Register("login", config.HandlerLogin)
-	cmds.Register("register", config.HandlerRegister)
-	cmds.Register("reset", config.HandlerReset)
-	cmds.Register("users", config.HandlerGetUsers)
-	cmds.Register("agg", config.HandlerAggregate)
-	cmds.Register("addfeed", config.MiddlewareLoggedIn(config.HandlerAddFeed))
-	cmds.Register("feeds", config.HandlerGetFeeds)
-	cmds.Register("follow", config.MiddlewareLoggedIn(config.HandlerFollow))
-	cmds.Register("following", config.MiddlewareLoggedIn(config.HandlerFollowing))
-	cmds.Register("unfollow", config.MiddlewareLoggedIn(config.HandlerUnfollow))
-	cmds.Register("browse", config.MiddlewareLoggedIn(config.HandlerBrowse))
-
-	args := os.Args
-	if len(args) < 2 {
-		fmt.Println("error: not enough arguments")
-		os.Exit(1)
+	if globals.Output != "" {
+		err = s.SetOutput(globals.Output)
+		if err != nil {
+			exit(err, globals.Debug)
+		}
 	}
 
 	cmd := config.Command{
-		Name:      args[1],
-		Arguments: args[2:],
+		Name:      args[0],
+		Arguments: args[1:],
 	}
 	err = cmds.Run(&s, cmd)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		exit(err, globals.Debug || s.Debug())
 	}
 }
+
+// Prints err and exits with the code for its kind
+func exit(err error, debug bool) {
+	fmt.Fprintln(os.Stderr, apperr.Message(err, debug))
+	os.Exit(apperr.ExitCode(err))
+}
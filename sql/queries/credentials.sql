@@ -0,0 +1,26 @@
+-- name: SetFeedCredential :one
+INSERT INTO feed_credentials (feed_id, created_at, updated_at, auth_type, auth_name, secret)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    auth_type = EXCLUDED.auth_type,
+    auth_name = EXCLUDED.auth_name,
+    secret = EXCLUDED.secret
+RETURNING *;
+
+-- name: GetFeedCredentialByUrl :one
+SELECT feed_credentials.* FROM feed_credentials
+INNER JOIN feeds
+ON feed_credentials.feed_id = feeds.id
+WHERE feeds.url = $1;
+
+-- name: DeleteFeedCredential :execrows
+DELETE FROM feed_credentials
+WHERE feed_id = $1;
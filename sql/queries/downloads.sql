@@ -0,0 +1,68 @@
+-- name: GetEnclosuresForUser :many
+SELECT
+    post_enclosures.*,
+    posts.title AS post_title,
+    posts.published_at,
+    feeds.name AS feed_name,
+    enclosure_downloads.path AS downloaded_path
+FROM post_enclosures
+INNER JOIN posts
+ON post_enclosures.post_id = posts.id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+INNER JOIN feed_follows
+ON feed_follows.feed_id = feeds.id
+LEFT JOIN enclosure_downloads
+ON enclosure_downloads.enclosure_id = post_enclosures.id
+AND enclosure_downloads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.published_at DESC
+LIMIT $2;
+
+-- name: GetPendingEnclosuresForUser :many
+SELECT
+    post_enclosures.*,
+    posts.title AS post_title,
+    posts.published_at,
+    feeds.name AS feed_name
+FROM post_enclosures
+INNER JOIN posts
+ON post_enclosures.post_id = posts.id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+INNER JOIN feed_follows
+ON feed_follows.feed_id = feeds.id
+LEFT JOIN enclosure_downloads
+ON enclosure_downloads.enclosure_id = post_enclosures.id
+AND enclosure_downloads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND enclosure_downloads.enclosure_id IS NULL
+AND posts.created_at >= $2
+ORDER BY posts.published_at;
+
+-- name: GetEnclosuresForPost :many
+SELECT
+    post_enclosures.*,
+    posts.title AS post_title,
+    posts.published_at,
+    feeds.name AS feed_name
+FROM post_enclosures
+INNER JOIN posts
+ON post_enclosures.post_id = posts.id
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+WHERE post_enclosures.post_id = $1;
+
+-- name: MarkEnclosureDownloaded :exec
+INSERT INTO enclosure_downloads (user_id, enclosure_id, downloaded_at, path, bytes)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (user_id, enclosure_id) DO UPDATE
+SET downloaded_at = EXCLUDED.downloaded_at,
+    path = EXCLUDED.path,
+    bytes = EXCLUDED.bytes;
@@ -25,5 +25,85 @@ WHERE id = $1
 RETURNING *;
 
 -- name: GetNextFeedToFetch :one
-SELECT * FROM feedss
-ORDER BY last_fetched_at DESC NULLS FIRST;
+SELECT * FROM feeds
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT 1;
+
+-- name: CountFeeds :one
+SELECT COUNT(*) FROM feeds;
+
+-- name: CountOverdueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE last_fetched_at IS NULL OR last_fetched_at < sqlc.arg(cutoff);
+
+-- name: SetFeedFullContent :one
+UPDATE feeds
+SET fetch_full_content = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedRetention :one
+UPDATE feeds
+SET retention_days = $2,
+    retention_max_posts = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: GetFeedUsage :one
+SELECT
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = $1) AS posts,
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = $1) AS followers;
+
+-- name: GetSavedPostCounts :one
+SELECT
+    (SELECT COUNT(*) FROM post_states
+        INNER JOIN posts ON post_states.post_id = posts.id
+        WHERE posts.feed_id = $1
+        AND post_states.user_id = $2
+        AND post_states.starred) AS starred,
+    (SELECT COUNT(*) FROM enclosure_downloads
+        INNER JOIN post_enclosures ON enclosure_downloads.enclosure_id = post_enclosures.id
+        INNER JOIN posts ON post_enclosures.post_id = posts.id
+        WHERE posts.feed_id = $1
+        AND enclosure_downloads.user_id = $2) AS downloads;
+
+-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: DeleteFeedIfOrphaned :execrows
+DELETE FROM feeds
+WHERE id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+);
+
+-- name: DeleteOrphanedFeeds :many
+DELETE FROM feeds
+WHERE NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+)
+RETURNING name;
+
+-- name: GetOrphanedFeeds :many
+SELECT name FROM feeds
+WHERE NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id
+);
+
+-- name: TransferFeeds :execrows
+UPDATE feeds
+SET user_id = sqlc.arg(to_user_id),
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = sqlc.arg(from_user_id);
@@ -0,0 +1,25 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (id, feed_id, fetched_at, status_code, content_encoding, wire_bytes, decoded_bytes)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+);
+
+-- name: GetFeedBandwidthStats :many
+SELECT
+    feeds.name,
+    feeds.url,
+    COUNT(feed_fetches.id) AS fetches,
+    CAST(COALESCE(SUM(feed_fetches.wire_bytes), 0) AS BIGINT) AS wire_bytes,
+    CAST(COALESCE(SUM(feed_fetches.decoded_bytes), 0) AS BIGINT) AS decoded_bytes
+FROM feeds
+LEFT JOIN feed_fetches
+ON feed_fetches.feed_id = feeds.id
+AND feed_fetches.fetched_at >= $1
+GROUP BY feeds.id, feeds.name, feeds.url
+ORDER BY wire_bytes DESC, feeds.name;
@@ -0,0 +1,32 @@
+-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, created_at, user_id, feed_id, title_pattern, author_pattern, keyword, action)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: GetFilterRulesForUser :many
+SELECT filter_rules.*, feeds.url AS feed_url FROM filter_rules
+LEFT JOIN feeds
+ON filter_rules.feed_id = feeds.id
+WHERE filter_rules.user_id = $1
+ORDER BY filter_rules.created_at;
+
+-- name: GetFilterRulesForFeed :many
+SELECT filter_rules.* FROM filter_rules
+INNER JOIN feed_follows
+ON filter_rules.user_id = feed_follows.user_id
+WHERE feed_follows.feed_id = sqlc.arg(feed_id)
+AND (filter_rules.feed_id IS NULL OR filter_rules.feed_id = sqlc.arg(feed_id))
+ORDER BY filter_rules.created_at;
+
+-- name: DeleteFilterRule :exec
+DELETE FROM filter_rules
+WHERE id = $1;
@@ -21,16 +21,28 @@ ON inserted_feed_follow.feed_id = feeds.id
 INNER JOIN users
 ON inserted_feed_follow.user_id = users.id;
 
+-- name: GetFeedFollow :one
+SELECT feed_follows.* FROM feed_follows
+INNER JOIN feeds
+ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+AND feeds.url = $2;
+
 -- name: GetFeedFollowsForUser :many
 SELECT
-    feeds.name AS feed_name,
+    feed_follows.id,
+    feed_follows.feed_id,
+    COALESCE(NULLIF(feed_follows.display_name, ''), feeds.name) AS feed_name,
+    feeds.url AS feed_url,
+    feed_follows.notes,
     users.name AS user_name
 FROM feed_follows
 INNER JOIN feeds
 ON feed_follows.feed_id = feeds.id
 INNER JOIN users
 ON feed_follows.user_id = users.id
-WHERE feed_follows.user_id = $1;
+WHERE feed_follows.user_id = $1
+ORDER BY feed_name;
 
 -- name: Unfollow :one
 DELETE FROM feed_follows
@@ -41,3 +53,15 @@ AND feed_id IN (
     WHERE feeds.url = $2 
 )
 RETURNING *;
+
+-- name: RenameFeedFollow :exec
+UPDATE feed_follows
+SET display_name = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: SetFeedFollowNotes :exec
+UPDATE feed_follows
+SET notes = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
@@ -1,5 +1,6 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id,
+    guid, guid_is_permalink, author, content_encoded, comments, identity_key, link_key)
 VALUES (
     $1,
     $2,
@@ -8,15 +9,109 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14,
+    $15
 )
+ON CONFLICT (feed_id, identity_key) DO NOTHING
 RETURNING *;
 
 -- name: GetPostsForUser :many
-SELECT * FROM posts
-WHERE feed_id IN(
+SELECT sqlc.embed(posts),
+    COALESCE(post_states.starred, false) AS starred,
+    post_states.read_at,
+    COALESCE(post_states.hidden, false) AS hidden
+FROM posts
+LEFT JOIN post_states
+ON post_states.post_id = posts.id
+AND post_states.user_id = $1
+WHERE posts.feed_id IN(
     SELECT feed_id FROM feed_follows
     WHERE user_id = $1
+    AND (CAST(sqlc.arg(tag) AS TEXT) = '' OR EXISTS (
+        SELECT 1 FROM follow_tags
+        WHERE follow_tags.feed_follow_id = feed_follows.id
+        AND (follow_tags.name = sqlc.arg(tag) OR follow_tags.name LIKE sqlc.arg(tag) || '/%')
+    ))
 )
-ORDER BY published_at DESC
-LIMIT $2;
\ No newline at end of file
+AND (CAST(sqlc.arg(include_hidden) AS BOOLEAN) OR NOT COALESCE(post_states.hidden, false))
+ORDER BY posts.published_at DESC
+LIMIT $2
+OFFSET sqlc.arg(offset);
+
+-- name: GetPostsByIDPrefix :many
+SELECT posts.* FROM posts
+INNER JOIN feed_follows
+ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND left(CAST(posts.id AS TEXT), length(CAST(sqlc.arg(id_prefix) AS TEXT))) = CAST(sqlc.arg(id_prefix) AS TEXT)
+LIMIT 2;
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GetFeedsForLinks :many
+SELECT posts.link_key, posts.feed_id, COALESCE(NULLIF(feed_follows.display_name, ''), feeds.name) AS name FROM posts
+INNER JOIN feeds
+ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows
+ON feed_follows.feed_id = feeds.id
+AND feed_follows.user_id = sqlc.arg(user_id)
+WHERE posts.link_key IN (SELECT jsonb_array_elements_text(CAST(sqlc.arg(link_keys) AS JSONB)))
+ORDER BY name;
+
+-- name: GetLinkKeyedPosts :many
+SELECT id, identity_key FROM posts
+WHERE identity_key LIKE 'link:%?%';
+
+-- name: SetPostIdentityKey :execrows
+UPDATE posts
+SET identity_key = $2
+WHERE id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM posts other
+    WHERE other.feed_id = posts.feed_id
+    AND other.identity_key = $2
+);
+
+-- name: CreatePostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: GetPostCategories :many
+SELECT name FROM post_categories
+WHERE post_id = $1
+ORDER BY name;
+
+-- name: CreatePostEnclosure :exec
+INSERT INTO post_enclosures (id, post_id, url, type, length)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: GetPostEnclosures :many
+SELECT * FROM post_enclosures
+WHERE post_id = $1;
+
+-- name: GetPostsWithoutLinkKey :many
+SELECT id, url FROM posts
+WHERE link_key = ''
+AND url <> '';
+
+-- name: SetPostLinkKey :exec
+UPDATE posts
+SET link_key = $2
+WHERE id = $1;
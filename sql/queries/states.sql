@@ -0,0 +1,47 @@
+-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred = EXCLUDED.starred;
+
+-- name: MarkPostRead :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(post_states.read_at, EXCLUDED.read_at);
+
+-- name: SetPostHidden :exec
+INSERT INTO post_states (user_id, post_id, hidden)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET hidden = EXCLUDED.hidden;
+
+-- name: GetPrunablePostsForFeed :many
+SELECT ranked.id, ranked.title, ranked.published_at FROM (
+    SELECT
+        posts.id,
+        posts.title,
+        posts.published_at,
+        ROW_NUMBER() OVER (ORDER BY posts.published_at DESC) AS position
+    FROM posts
+    WHERE posts.feed_id = sqlc.arg(feed_id)
+) ranked
+WHERE (ranked.published_at < sqlc.arg(cutoff) OR ranked.position > sqlc.arg(keep_newest))
+AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.post_id = ranked.id
+    AND post_states.starred
+)
+AND NOT (sqlc.arg(keep_unread) AND EXISTS (
+    SELECT 1 FROM feed_follows
+    LEFT JOIN post_states
+    ON post_states.post_id = ranked.id
+    AND post_states.user_id = feed_follows.user_id
+    WHERE feed_follows.feed_id = sqlc.arg(feed_id)
+    AND post_states.read_at IS NULL
+))
+ORDER BY ranked.published_at;
+
+-- name: DeletePost :exec
+DELETE FROM posts
+WHERE id = $1;
@@ -0,0 +1,15 @@
+-- name: AddFollowTag :exec
+INSERT INTO follow_tags (feed_follow_id, name)
+VALUES ($1, $2)
+ON CONFLICT (feed_follow_id, name) DO NOTHING;
+
+-- name: RemoveFollowTag :execrows
+DELETE FROM follow_tags
+WHERE feed_follow_id = $1 AND name = $2;
+
+-- name: GetFollowTagsForUser :many
+SELECT follow_tags.* FROM follow_tags
+INNER JOIN feed_follows
+ON follow_tags.feed_follow_id = feed_follows.id
+WHERE feed_follows.user_id = $1
+ORDER BY follow_tags.name;
@@ -1,10 +1,11 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, role)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    CASE WHEN EXISTS (SELECT 1 FROM users) THEN 'member' ELSE 'admin' END
 )
 RETURNING *;
 
@@ -13,11 +14,39 @@ SELECT * FROM users
 WHERE name = $1;
 
 -- name: GetUsers :many
-SELECT name FROM users;
+SELECT * FROM users
+ORDER BY name;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin';
 
 -- name: MatchUser :one
 SELECT name FROM users
 WHERE id = $1;
 
 -- name: ResetUsers :exec
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
+-- name: RenameUser :one
+UPDATE users
+SET name = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserRole :exec
+UPDATE users
+SET role = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: GetUserSuccessor :one
+SELECT * FROM users
+WHERE id <> $1
+ORDER BY role = 'admin' DESC, created_at
+LIMIT 1;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE feed_credentials (
+    feed_id UUID PRIMARY KEY REFERENCES feeds
+        ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    auth_type TEXT NOT NULL,
+    auth_name TEXT NOT NULL,
+    secret BYTEA NOT NULL
+);
+
+-- +goose Down
+DROP TABLE feed_credentials;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE feed_fetches (
+    id UUID PRIMARY KEY,
+    feed_id UUID NOT NULL REFERENCES feeds
+        ON DELETE CASCADE,
+    fetched_at TIMESTAMP NOT NULL,
+    status_code INTEGER NOT NULL,
+    content_encoding TEXT NOT NULL,
+    wire_bytes BIGINT NOT NULL,
+    decoded_bytes BIGINT NOT NULL
+);
+
+CREATE INDEX feed_fetches_feed_id_idx ON feed_fetches (feed_id, fetched_at);
+
+-- +goose Down
+DROP TABLE feed_fetches;
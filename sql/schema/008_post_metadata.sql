@@ -0,0 +1,34 @@
+-- +goose Up
+ALTER TABLE posts
+ADD guid TEXT NOT NULL DEFAULT '',
+ADD guid_is_permalink BOOLEAN NOT NULL DEFAULT false,
+ADD author TEXT NOT NULL DEFAULT '',
+ADD content_encoded TEXT NOT NULL DEFAULT '',
+ADD comments TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE post_categories (
+    post_id UUID NOT NULL REFERENCES posts
+        ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    PRIMARY KEY (post_id, name)
+);
+
+CREATE TABLE post_enclosures (
+    id UUID PRIMARY KEY,
+    post_id UUID NOT NULL REFERENCES posts
+        ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    type TEXT NOT NULL,
+    length BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE post_enclosures;
+DROP TABLE post_categories;
+
+ALTER TABLE posts
+DROP COLUMN guid,
+DROP COLUMN guid_is_permalink,
+DROP COLUMN author,
+DROP COLUMN content_encoded,
+DROP COLUMN comments;
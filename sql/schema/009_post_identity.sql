@@ -0,0 +1,39 @@
+-- +goose Up
+ALTER TABLE posts
+ADD identity_key TEXT NOT NULL DEFAULT '';
+
+UPDATE posts
+SET identity_key = CASE
+    WHEN guid <> '' THEN 'guid:' || guid
+    ELSE 'link:' || url
+END;
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_url_key;
+
+ALTER TABLE posts
+ADD CONSTRAINT posts_feed_id_identity_key_key UNIQUE (feed_id, identity_key);
+
+CREATE INDEX posts_url_idx ON posts (url);
+
+-- +goose Down
+DROP INDEX posts_url_idx;
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_identity_key_key;
+
+-- keep the oldest copy of each url so the old constraint can come back
+DELETE FROM posts
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY url ORDER BY created_at, id) AS copy
+        FROM posts
+    ) copies
+    WHERE copy > 1
+);
+
+ALTER TABLE posts
+ADD CONSTRAINT posts_url_key UNIQUE (url);
+
+ALTER TABLE posts
+DROP COLUMN identity_key;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE enclosure_downloads (
+    user_id UUID NOT NULL REFERENCES users
+        ON DELETE CASCADE,
+    enclosure_id UUID NOT NULL REFERENCES post_enclosures
+        ON DELETE CASCADE,
+    downloaded_at TIMESTAMP NOT NULL,
+    path TEXT NOT NULL,
+    bytes BIGINT NOT NULL,
+    PRIMARY KEY (user_id, enclosure_id)
+);
+
+-- +goose Down
+DROP TABLE enclosure_downloads;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD retention_days INTEGER,
+ADD retention_max_posts INTEGER;
+
+CREATE TABLE post_states (
+    user_id UUID NOT NULL REFERENCES users
+        ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts
+        ON DELETE CASCADE,
+    starred BOOLEAN NOT NULL DEFAULT false,
+    read_at TIMESTAMP,
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_states;
+
+ALTER TABLE feeds
+DROP COLUMN retention_days,
+DROP COLUMN retention_max_posts;
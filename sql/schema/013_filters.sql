@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE filter_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users
+        ON DELETE CASCADE,
+    feed_id UUID REFERENCES feeds
+        ON DELETE CASCADE,
+    title_pattern TEXT NOT NULL DEFAULT '',
+    author_pattern TEXT NOT NULL DEFAULT '',
+    keyword TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL
+);
+
+ALTER TABLE post_states
+ADD hidden BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE post_states
+DROP COLUMN hidden;
+
+DROP TABLE filter_rules;
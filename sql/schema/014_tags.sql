@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE follow_tags (
+    feed_follow_id UUID NOT NULL REFERENCES feed_follows
+        ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    PRIMARY KEY (feed_follow_id, name)
+);
+
+-- +goose Down
+DROP TABLE follow_tags;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feed_follows
+ADD display_name TEXT NOT NULL DEFAULT '',
+ADD notes TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN display_name,
+DROP COLUMN notes;
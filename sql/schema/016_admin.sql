@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE users
+ADD is_admin BOOLEAN NOT NULL DEFAULT false;
+
+-- the first user to register runs the place
+UPDATE users
+SET is_admin = true
+WHERE id = (
+    SELECT id FROM users
+    ORDER BY created_at
+    LIMIT 1
+);
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN is_admin;
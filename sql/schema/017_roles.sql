@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users
+ADD role TEXT NOT NULL DEFAULT 'member'
+    CHECK (role IN ('admin', 'member', 'read-only'));
+
+UPDATE users
+SET role = 'admin'
+WHERE is_admin;
+
+ALTER TABLE users
+DROP COLUMN is_admin;
+
+-- +goose Down
+ALTER TABLE users
+ADD is_admin BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE users
+SET is_admin = true
+WHERE role = 'admin';
+
+ALTER TABLE users
+DROP COLUMN role;
@@ -0,0 +1,8 @@
+// Package schema holds gator's database migrations, embedded so gator can
+// apply them itself
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,156 @@
+-- SQLite starts from the schema Postgres reached in 017_roles.sql,
+-- later migrations go in both schema directories with the same version
+
+-- +goose Up
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL UNIQUE,
+    role TEXT NOT NULL DEFAULT 'member'
+        CHECK (role IN ('admin', 'member', 'read-only'))
+);
+
+CREATE TABLE feeds (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL UNIQUE,
+    user_id TEXT NOT NULL REFERENCES users (id)
+        ON DELETE CASCADE,
+    last_fetched_at TIMESTAMP,
+    fetch_full_content BOOLEAN NOT NULL DEFAULT false,
+    retention_days INTEGER,
+    retention_max_posts INTEGER
+);
+
+CREATE TABLE feed_follows (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id)
+        ON DELETE CASCADE,
+    feed_id TEXT NOT NULL REFERENCES feeds (id)
+        ON DELETE CASCADE,
+    display_name TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    UNIQUE (user_id, feed_id)
+);
+
+CREATE TABLE posts (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT NOT NULL,
+    published_at TIMESTAMP NOT NULL,
+    feed_id TEXT NOT NULL REFERENCES feeds (id)
+        ON DELETE CASCADE,
+    guid TEXT NOT NULL DEFAULT '',
+    guid_is_permalink BOOLEAN NOT NULL DEFAULT false,
+    author TEXT NOT NULL DEFAULT '',
+    content_encoded TEXT NOT NULL DEFAULT '',
+    comments TEXT NOT NULL DEFAULT '',
+    identity_key TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL DEFAULT '',
+    UNIQUE (feed_id, identity_key)
+);
+
+CREATE INDEX posts_url_idx ON posts (url);
+
+CREATE TABLE feed_credentials (
+    feed_id TEXT PRIMARY KEY REFERENCES feeds (id)
+        ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    auth_type TEXT NOT NULL,
+    auth_name TEXT NOT NULL,
+    secret BLOB NOT NULL
+);
+
+CREATE TABLE feed_fetches (
+    id TEXT PRIMARY KEY,
+    feed_id TEXT NOT NULL REFERENCES feeds (id)
+        ON DELETE CASCADE,
+    fetched_at TIMESTAMP NOT NULL,
+    status_code INTEGER NOT NULL,
+    content_encoding TEXT NOT NULL,
+    wire_bytes INTEGER NOT NULL,
+    decoded_bytes INTEGER NOT NULL
+);
+
+CREATE INDEX feed_fetches_feed_id_idx ON feed_fetches (feed_id, fetched_at);
+
+CREATE TABLE post_categories (
+    post_id TEXT NOT NULL REFERENCES posts (id)
+        ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    PRIMARY KEY (post_id, name)
+);
+
+CREATE TABLE post_enclosures (
+    id TEXT PRIMARY KEY,
+    post_id TEXT NOT NULL REFERENCES posts (id)
+        ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    type TEXT NOT NULL,
+    length INTEGER NOT NULL
+);
+
+CREATE TABLE enclosure_downloads (
+    user_id TEXT NOT NULL REFERENCES users (id)
+        ON DELETE CASCADE,
+    enclosure_id TEXT NOT NULL REFERENCES post_enclosures (id)
+        ON DELETE CASCADE,
+    downloaded_at TIMESTAMP NOT NULL,
+    path TEXT NOT NULL,
+    bytes INTEGER NOT NULL,
+    PRIMARY KEY (user_id, enclosure_id)
+);
+
+CREATE TABLE post_states (
+    user_id TEXT NOT NULL REFERENCES users (id)
+        ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts (id)
+        ON DELETE CASCADE,
+    starred BOOLEAN NOT NULL DEFAULT false,
+    read_at TIMESTAMP,
+    hidden BOOLEAN NOT NULL DEFAULT false,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE filter_rules (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id)
+        ON DELETE CASCADE,
+    feed_id TEXT REFERENCES feeds (id)
+        ON DELETE CASCADE,
+    title_pattern TEXT NOT NULL DEFAULT '',
+    author_pattern TEXT NOT NULL DEFAULT '',
+    keyword TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL
+);
+
+CREATE TABLE follow_tags (
+    feed_follow_id TEXT NOT NULL REFERENCES feed_follows (id)
+        ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    PRIMARY KEY (feed_follow_id, name)
+);
+
+-- +goose Down
+DROP TABLE follow_tags;
+DROP TABLE filter_rules;
+DROP TABLE post_states;
+DROP TABLE enclosure_downloads;
+DROP TABLE post_enclosures;
+DROP TABLE post_categories;
+DROP TABLE feed_fetches;
+DROP TABLE feed_credentials;
+DROP TABLE posts;
+DROP TABLE feed_follows;
+DROP TABLE feeds;
+DROP TABLE users;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- the link with tracking params stripped, filled in by gator migrate up
+-- since the params it strips can't be worked out in SQL
+ALTER TABLE posts
+ADD link_key TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX posts_link_key_idx ON posts (link_key);
+
+-- +goose Down
+DROP INDEX posts_link_key_idx;
+
+ALTER TABLE posts
+DROP COLUMN link_key;
@@ -0,0 +1,7 @@
+// Package schema holds the SQLite version of gator's migrations
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS